@@ -1,51 +1,118 @@
 package configs
 
 import (
-	"gopkg.in/yaml.v3"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 )
 
-// Configs holds application configuration
+// Configs holds application configuration. Besides its own keys it reads
+// the keys of Nameko config files, so one file can drive Go and Python
+// services. Zero values leave the defaults of the rpc package in place.
 type Configs struct {
 	ServiceName  string `yaml:"service_name"`
 	RabbitMQURL  string `yaml:"rabbitmq_url"`
 	ExchangeName string `yaml:"exchange_name"`
+
+	AMQPURI            string     `yaml:"AMQP_URI"`     // Nameko's name for rabbitmq_url, used if that is empty
+	RPCExchange        string     `yaml:"rpc_exchange"` // Nameko's name for exchange_name, used if that is empty
+	MaxWorkers         int        `yaml:"max_workers"`
+	ParentCallsTracked int        `yaml:"parent_calls_tracked"`
+	Serializer         string     `yaml:"serializer"`
+	Heartbeat          *int       `yaml:"HEARTBEAT"` // Seconds, nil if unset since 0 turns the client heartbeat off
+	PrefetchCount      int        `yaml:"PREFETCH_COUNT"`
+	AMQPSSL            *SSLConfig `yaml:"AMQP_SSL"`
 }
 
 var configs *Configs
 
-// LoadConfigs loads the configuration once
-func LoadConfigs() {
-	configPath := findConfigPath()
-	if configPath == "" {
-		log.Fatal("config.yaml not found in project root or any parent directory")
+// DefaultExchangeName is the RPC exchange used when neither exchange_name nor
+// rpc_exchange is set.
+const DefaultExchangeName = "nameko-rpc"
+
+// ErrNotFound is returned by Load when no path is given and no config.yaml
+// is found.
+var ErrNotFound = errors.New("config.yaml not found in project root or any parent directory")
+
+// LoadOptions configures Load.
+type LoadOptions struct {
+	// Strict reports keys that are neither namego nor Nameko keys as
+	// invalid. Leave it off for config files shared with Nameko services,
+	// which contain keys of their own.
+	Strict bool
+}
+
+// Load reads the configuration at path, or the config.yaml found by
+// searching from CONFIG_PATH and the working directory upwards if path is
+// empty, applies environment overrides and validates it. Invalid fields are
+// reported together in a *ValidationError.
+func Load(path string, options ...LoadOptions) (*Configs, error) {
+	var opts LoadOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	if path == "" {
+		var err error
+		if path, err = findConfigPath(); err != nil {
+			return nil, err
+		}
+		if path == "" {
+			return nil, ErrNotFound
+		}
 	}
 
-	file, err := os.Open(configPath)
+	file, err := os.Open(path)
 	if err != nil {
-		log.Fatalf("failed to open config file %s: %v", configPath, err)
+		return nil, err
 	}
 	defer file.Close()
 
-	configs = &Configs{}
-	decoder := yaml.NewDecoder(file)
-	if err := decoder.Decode(configs); err != nil {
-		log.Fatalf("failed to decode config file: %v", err)
+	cfg := &Configs{}
+	unknown, err := decode(file, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode config file %s: %w", path, err)
+	}
+
+	var invalid []FieldError
+	if opts.Strict {
+		for _, key := range unknown {
+			invalid = append(invalid, FieldError{Field: key, Message: "unknown key"})
+		}
+	}
+	invalid = append(invalid, applyEnvironment(cfg)...)
+
+	cfg.applyNamekoKeys()
+	if cfg.ExchangeName == "" {
+		cfg.ExchangeName = DefaultExchangeName
 	}
 
-	if configs.ExchangeName == "" {
-		configs.ExchangeName = "nameko-rpc"
+	invalid = append(invalid, cfg.validate()...)
+	if len(invalid) > 0 {
+		return nil, &ValidationError{Path: path, Fields: invalid}
 	}
 
-	// Validate required fields
-	if configs.ServiceName == "" || configs.RabbitMQURL == "" {
-		log.Fatal("missing required configuration fields in config.yaml")
+	return cfg, nil
+}
+
+// LoadConfigs loads the configuration found by searching for config.yaml,
+// for GetConfigs. It exits the process if the configuration cannot be loaded.
+//
+// Deprecated: use Load to handle the error instead.
+func LoadConfigs() {
+	cfg, err := Load("")
+	if err != nil {
+		log.Fatal(err)
 	}
+	configs = cfg
 }
 
-// GetConfigs returns the loaded configuration
+// GetConfigs returns the loaded configuration, loading it on first use.
+//
+// Deprecated: GetConfigs exits the process if the configuration cannot be
+// loaded, use Load to handle the error instead.
 func GetConfigs() *Configs {
 	if configs == nil {
 		LoadConfigs()
@@ -53,20 +120,21 @@ func GetConfigs() *Configs {
 	return configs
 }
 
-// findConfigPath searches for config.yaml in project root or parent directories
-func findConfigPath() string {
+// findConfigPath searches for config.yaml in project root or parent
+// directories, returning "" if there is none.
+func findConfigPath() (string, error) {
 	// Check if CONFIG_PATH is set
 	if envPath, exists := os.LookupEnv("CONFIG_PATH"); exists {
-		return envPath
+		return envPath, nil
 	}
 
 	// Start searching from the current working directory
 	workingDir, err := os.Getwd()
 	if err != nil {
-		log.Fatalf("failed to get working directory: %v", err)
+		return "", fmt.Errorf("failed to get working directory: %w", err)
 	}
 
-	return searchConfigFile(workingDir)
+	return searchConfigFile(workingDir), nil
 }
 
 // searchConfigFile recursively goes up directories to find config.yaml
@@ -0,0 +1,151 @@
+package configs
+
+import (
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// EnvironmentPrefix prefixes the environment variables overriding config
+// fields, NAMEGO_RABBITMQ_URL overrides rabbitmq_url.
+const EnvironmentPrefix = "NAMEGO_"
+
+// interpolation matches Nameko's "${VAR}" and "${VAR:default}" references.
+var interpolation = regexp.MustCompile(`\$\{([^}{:]+)(?::([^}{]*))?\}`)
+
+// decode parses YAML from r into v, replacing environment variable references
+// in scalar values first. It returns the top-level keys v has no field for.
+func decode(r io.Reader, v interface{}) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if document.Kind == 0 {
+		return nil, nil // Empty file
+	}
+
+	interpolate(&document)
+	if err := document.Decode(v); err != nil {
+		return nil, err
+	}
+
+	return unknownKeys(&document, v), nil
+}
+
+// unknownKeys returns the keys of the top-level mapping of document that do
+// not match a yaml tag of the struct v points to.
+func unknownKeys(document *yaml.Node, v interface{}) []string {
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+
+	known := make(map[string]bool)
+	target := reflect.TypeOf(v).Elem()
+	for i := 0; i < target.NumField(); i++ {
+		known[yamlKey(target.Field(i))] = true
+	}
+
+	var unknown []string
+	mapping := document.Content[0].Content
+	for i := 0; i < len(mapping); i += 2 {
+		if key := mapping[i].Value; !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	return unknown
+}
+
+func yamlKey(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("yaml"), ",")[0]
+}
+
+// interpolate replaces "${VAR:default}" references in the scalars below node
+// with the value of VAR, or default if VAR is not set. Like Nameko, plain
+// scalars are resolved again afterwards, so "${WORKERS:10}" decodes into an
+// int while quoted scalars stay strings.
+func interpolate(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode {
+		if !interpolation.MatchString(node.Value) {
+			return
+		}
+		node.Value = expand(node.Value)
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+		return
+	}
+
+	for _, child := range node.Content {
+		interpolate(child)
+	}
+}
+
+func expand(value string) string {
+	return interpolation.ReplaceAllStringFunc(value, func(reference string) string {
+		match := interpolation.FindStringSubmatch(reference)
+		if env, ok := os.LookupEnv(strings.TrimSpace(match[1])); ok {
+			return env
+		}
+		return match[2]
+	})
+}
+
+// applyEnvironment overrides every field of cfg that has a yaml tag with the
+// environment variable named EnvironmentPrefix plus the upper-cased tag. It
+// returns the variables whose values could not be parsed.
+func applyEnvironment(cfg *Configs) []FieldError {
+	target := reflect.ValueOf(cfg).Elem()
+
+	var invalid []FieldError
+	for i := 0; i < target.NumField(); i++ {
+		key := yamlKey(target.Type().Field(i))
+		if key == "" || key == "-" {
+			continue
+		}
+
+		name := EnvironmentPrefix + strings.ToUpper(key)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		if err := setField(target.Field(i), value); err != nil {
+			invalid = append(invalid, FieldError{Field: name, Message: err.Error()})
+		}
+	}
+
+	return invalid
+}
+
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(parsed)
+	default:
+		// Structured values, e.g. AMQP_SSL, are given as YAML.
+		return yaml.Unmarshal([]byte(value), field.Addr().Interface())
+	}
+
+	return nil
+}
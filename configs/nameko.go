@@ -0,0 +1,71 @@
+package configs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+)
+
+// SSLConfig is Nameko's AMQP_SSL setting. It is either `true`, to use TLS
+// with the system roots, or a mapping of certificate files.
+type SSLConfig struct {
+	Enabled  bool   `yaml:"-"`
+	CACerts  string `yaml:"ca_certs"` // PEM bundle of CAs to verify the broker with, the system roots if empty
+	CertFile string `yaml:"certfile"` // Client certificate, together with KeyFile
+	KeyFile  string `yaml:"keyfile"`
+}
+
+func (c *SSLConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&c.Enabled)
+	}
+
+	type plain SSLConfig
+	if err := node.Decode((*plain)(c)); err != nil {
+		return err
+	}
+	c.Enabled = true
+	return nil
+}
+
+// TLSConfig returns the tls.Config described by c, or nil if TLS is disabled.
+func (c *SSLConfig) TLSConfig() (*tls.Config, error) {
+	if c == nil || !c.Enabled {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if c.CACerts != "" {
+		pem, err := os.ReadFile(c.CACerts)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CACerts)
+		}
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+// applyNamekoKeys fills the namego keys from their Nameko equivalents.
+func (c *Configs) applyNamekoKeys() {
+	if c.RabbitMQURL == "" {
+		c.RabbitMQURL = c.AMQPURI
+	}
+	if c.ExchangeName == "" {
+		c.ExchangeName = c.RPCExchange
+	}
+}
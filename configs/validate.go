@@ -0,0 +1,82 @@
+package configs
+
+import (
+	"fmt"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"strings"
+)
+
+// FieldError describes an invalid configuration key or environment variable.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationError lists every invalid field of a configuration file.
+type ValidationError struct {
+	Path   string
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Error()
+	}
+	return fmt.Sprintf("invalid configuration %s: %s", e.Path, strings.Join(messages, "; "))
+}
+
+// validate returns every invalid field of c, after Nameko keys and defaults
+// have been applied.
+func (c *Configs) validate() []FieldError {
+	var invalid []FieldError
+	add := func(field, format string, args ...interface{}) {
+		invalid = append(invalid, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(c.ServiceName) == "" {
+		add("service_name", "is required")
+	} else if strings.ContainsAny(c.ServiceName, " \t\n.") {
+		add("service_name", "%q must not contain whitespace or dots", c.ServiceName)
+	}
+
+	if c.RabbitMQURL == "" {
+		add("rabbitmq_url", "is required, or set AMQP_URI")
+	} else if _, err := amqp.ParseURI(c.RabbitMQURL); err != nil {
+		add("rabbitmq_url", "%v", err)
+	}
+
+	if strings.TrimSpace(c.ExchangeName) == "" {
+		add("exchange_name", "must not be blank")
+	}
+
+	for _, count := range []struct {
+		field string
+		value int
+	}{
+		{"max_workers", c.MaxWorkers},
+		{"parent_calls_tracked", c.ParentCallsTracked},
+		{"PREFETCH_COUNT", c.PrefetchCount},
+	} {
+		if count.value < 0 {
+			add(count.field, "must not be negative, got %d", count.value)
+		}
+	}
+	if c.Heartbeat != nil && *c.Heartbeat < 0 {
+		add("HEARTBEAT", "must not be negative, got %d", *c.Heartbeat)
+	}
+
+	if c.Serializer != "" && c.Serializer != "json" {
+		add("serializer", "%q is not supported, only json is", c.Serializer)
+	}
+
+	if c.AMQPSSL != nil && (c.AMQPSSL.CertFile == "") != (c.AMQPSSL.KeyFile == "") {
+		add("AMQP_SSL", "certfile and keyfile must be set together")
+	}
+
+	return invalid
+}
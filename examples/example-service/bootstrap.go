@@ -1,12 +1,13 @@
 package main
 
 import (
+	"github.com/joejoe-am/namego/pkg/rpc"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"log"
 )
 
-func InitRabbitMQ(RabbitMQURL string) *amqp.Connection {
-	conn, err := amqp.Dial(RabbitMQURL)
+func InitRabbitMQ(RabbitMQURL string, options rpc.Options) *amqp.Connection {
+	conn, err := rpc.Dial(RabbitMQURL, options)
 	if err != nil {
 		log.Fatalf("failed to establish RabbitMQ connection: %v", err)
 	}
@@ -2,9 +2,15 @@ package gateway
 
 import (
 	"fmt"
+	"github.com/joejoe-am/namego/pkg/web"
 	"github.com/valyala/fasthttp"
+	"time"
 )
 
-func LoggingMiddleware(ctx *fasthttp.RequestCtx) {
-	fmt.Printf("Request: %s %s\n", string(ctx.Method()), string(ctx.Path()))
+func LoggingMiddleware(next web.Handler) web.Handler {
+	return func(ctx *fasthttp.RequestCtx) {
+		start := time.Now()
+		next(ctx)
+		fmt.Printf("Request: %s %s %d %s\n", string(ctx.Method()), string(ctx.Path()), ctx.Response.StatusCode(), time.Since(start))
+	}
 }
@@ -4,19 +4,25 @@ import (
 	"context"
 	"fmt"
 	"github.com/joejoe-am/namego/configs"
+	"github.com/joejoe-am/namego/examples/example-service/gateway"
 	"github.com/joejoe-am/namego/examples/example-service/service"
 	"github.com/joejoe-am/namego/pkg/rpc"
+	"github.com/joejoe-am/namego/pkg/web"
 	"log"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 )
 
 // TODO: change package name
 
 func main() {
-	cfg := configs.GetConfigs()
+	cfg, err := configs.Load("")
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -26,11 +32,16 @@ func main() {
 
 	var wg sync.WaitGroup
 
-	amqpConnection := InitRabbitMQ(cfg.RabbitMQURL)
+	rpcOptions, err := rpc.OptionsFromConfigs(cfg)
+	if err != nil {
+		log.Fatalf("invalid RPC configuration: %v", err)
+	}
+
+	amqpConnection := InitRabbitMQ(cfg.RabbitMQURL, rpcOptions)
 	defer amqpConnection.Close()
 
 	// RPC client example
-	err := rpc.InitClient(amqpConnection)
+	err = rpc.InitClient(amqpConnection, rpcOptions)
 
 	if err != nil {
 		log.Fatal(err)
@@ -50,7 +61,7 @@ func main() {
 	fmt.Println(response, err)
 
 	// RPC server example
-	rpcServer := rpc.NewServer("nameko", amqpConnection)
+	rpcServer := rpc.NewServer(cfg.ServiceName, amqpConnection, rpcOptions)
 	rpcServer.RegisterMethod("multiply", service.Multiply)
 
 	//handlerConfig := events.EventConfig{
@@ -59,17 +70,22 @@ func main() {
 	//	HandlerType:      events.ServicePool,
 	//	ReliableDelivery: true,
 	//	HandlerFunction:  service.EventHandlerFunction,
-	//}
+	//	MethodName:       "handle_example",
+	//}.WithOptions(rpcOptions)
 	//
 	//eventHandler, err := events.NewEventHandler(handlerConfig)
 	//if err != nil {
 	//	log.Fatalf("failed to create event handler: %v", err)
 	//}
 	//
-	//err = eventHandler.Start(amqpConnection)
-	//if err != nil {
-	//	log.Fatalf("failed to start event handler: %v", err)
-	//}
+	//wg.Add(1)
+	//go func() {
+	//	defer wg.Done()
+	//	if err := eventHandler.Start(ctx, amqpConnection); err != nil {
+	//		log.Printf("event handler error: %v", err)
+	//		cancel()
+	//	}
+	//}()
 
 	// Dispatch event Example
 	service.DispatchEventExampleFunction(amqpConnection, cfg.ServiceName)
@@ -84,6 +100,26 @@ func main() {
 		}
 	}()
 
+	// Web server example
+	server := web.New(web.Config{
+		Addr:         ":8080",
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	})
+	server.Get("/health", gateway.HealthHandler, gateway.LoggingMiddleware)
+	server.Get("/auth/health", gateway.AuthHealthHandler(authRpc), gateway.LoggingMiddleware)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		fmt.Println("starting web server on :8080")
+		if err := server.Listen(""); err != nil {
+			log.Printf("Web server error: %v", err)
+			cancel()
+		}
+	}()
+
 	select {
 	case sig := <-signalChan:
 		log.Printf("Received signal: %v", sig)
@@ -91,17 +127,17 @@ func main() {
 	case <-ctx.Done():
 	}
 
-	//shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
-	//defer shutdownCancel()
-	//if err := server.Shutdown(shutdownCtx); err != nil {
-	//	log.Printf("Error shutting down web server: %v", err)
-	//}
-	//
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down web server: %v", err)
+	}
+
 	//if err := rpcServer.Stop(); err != nil {
 	//	log.Printf("Error shutting down RPC server: %v", err)
 	//}
 	//
-	//if err := eventHandler.Close(); err != nil {
+	//if err := eventHandler.Stop(shutdownCtx); err != nil {
 	//	log.Printf("Error shutting down event handler: %v", err)
 	//}
 	//
@@ -0,0 +1,131 @@
+// Package metrics holds the Prometheus collectors instrumenting RPC, events
+// and HTTP. The web/webmetrics package serves them and records HTTP requests.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "namego"
+
+// Registry holds every namego collector plus the Go runtime and process
+// collectors. Register application metrics here to expose them alongside.
+var Registry = prometheus.NewRegistry()
+
+var (
+	RPCClientRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "rpc_client",
+		Name:      "requests_total",
+		Help:      "RPC calls made, by target service, method and result (ok or the remote exc_type).",
+	}, []string{"service", "method", "result"})
+
+	RPCClientDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "rpc_client",
+		Name:      "duration_seconds",
+		Help:      "RPC call latency, by target service and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "method"})
+
+	RPCClientInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "rpc_client",
+		Name:      "in_flight",
+		Help:      "RPC calls awaiting a reply, by target service.",
+	}, []string{"service"})
+
+	RPCServerRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "rpc_server",
+		Name:      "requests_total",
+		Help:      "RPC requests handled, by service, method and result (ok or error).",
+	}, []string{"service", "method", "result"})
+
+	RPCServerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "rpc_server",
+		Name:      "duration_seconds",
+		Help:      "RPC request handling time, by service and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "method"})
+
+	RPCServerInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "rpc_server",
+		Name:      "in_flight",
+		Help:      "RPC requests being handled, by service and method.",
+	}, []string{"service", "method"})
+
+	RPCServerWorkers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "rpc_server",
+		Name:      "workers",
+		Help:      "Size of the RPC server worker pool, by service.",
+	}, []string{"service"})
+
+	EventsDispatched = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "events",
+		Name:      "dispatched_total",
+		Help:      "Events dispatched, by source service, event type and result (ok or error).",
+	}, []string{"source_service", "event_type", "result"})
+
+	EventsConsumed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "events",
+		Name:      "consumed_total",
+		Help:      "Events consumed, by handler queue, event type and outcome (ack, nack, retry or parked).",
+	}, []string{"queue", "event_type", "outcome"})
+
+	EventsHandlerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "events",
+		Name:      "handler_duration_seconds",
+		Help:      "Event handler latency, by handler queue.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"queue"})
+
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests served, by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency, by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		RPCClientRequests,
+		RPCClientDuration,
+		RPCClientInFlight,
+		RPCServerRequests,
+		RPCServerDuration,
+		RPCServerInFlight,
+		RPCServerWorkers,
+		EventsDispatched,
+		EventsConsumed,
+		EventsHandlerDuration,
+		HTTPRequests,
+		HTTPDuration,
+	)
+}
+
+// Result returns the result label for err, "ok" if it is nil.
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
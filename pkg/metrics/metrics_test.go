@@ -0,0 +1,15 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResult(t *testing.T) {
+	if result := Result(nil); result != "ok" {
+		t.Errorf("expected ok, got %q", result)
+	}
+	if result := Result(errors.New("boom")); result != "error" {
+		t.Errorf("expected error, got %q", result)
+	}
+}
@@ -1,19 +1,32 @@
 package rpc
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/joejoe-am/namego/pkg/metrics"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"log"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// publisher is the part of *amqp.Channel used to send requests.
+type publisher interface {
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+}
+
 var (
 	amqpChannel    *amqp.Channel
+	requests       publisher // amqpChannel, replaced in tests
 	replyQueueName string
 	replyQueueID   string
 	pendingReplies sync.Map // To track pending replies (correlation_id -> channel)
+	consuming      atomic.Bool
+	clientOptions  Options
 )
 
 // Client handles RPC communication with a target service.
@@ -23,14 +36,29 @@ type Client struct {
 
 // Response represents the result of an RPC call.
 type Response struct {
-	Result interface{} `json:"result"`
-	Error  *struct {
-		ExcType   string                 `json:"exc_type"`
-		ExcPath   string                 `json:"exc_path"`
-		ExcArgs   []interface{}          `json:"exc_args"`
-		ExcKwargs map[string]interface{} `json:"exc_kwargs"`
-		Value     string                 `json:"value"`
-	} `json:"error"`
+	Result interface{}  `json:"result"`
+	Error  *RemoteError `json:"error"`
+}
+
+// RemoteError is an exception raised by the remote service, serialized the
+// way Nameko does.
+type RemoteError struct {
+	ExcType   string                 `json:"exc_type"`
+	ExcPath   string                 `json:"exc_path"`
+	ExcArgs   []interface{}          `json:"exc_args"`
+	ExcKwargs map[string]interface{} `json:"exc_kwargs"`
+	Value     string                 `json:"value"`
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf(
+		"RPC Error: %s (type: %s, path: %s, args: %v, kwargs: %v)",
+		e.Value,
+		e.ExcType,
+		e.ExcPath,
+		e.ExcArgs,
+		e.ExcKwargs,
+	)
 }
 
 func NewClient(serviceName string) *Client {
@@ -39,14 +67,26 @@ func NewClient(serviceName string) *Client {
 	}
 }
 
-func InitClient(amqpConnection *amqp.Connection) error {
+// InitClient sets up the reply queue shared by all clients. Options default
+// to DefaultClientServiceName and DefaultExchangeName.
+func InitClient(amqpConnection *amqp.Connection, options ...Options) error {
 	var err error
 
+	clientOptions = Options{}
+	if len(options) > 0 {
+		clientOptions = options[0]
+	}
+	clientOptions = clientOptions.withDefaults()
+	if err = clientOptions.validate(); err != nil {
+		return err
+	}
+
 	amqpChannel, err = amqpConnection.Channel()
 	if err != nil {
 		log.Printf("error creating amqp channel: %v", err)
 		return err
 	}
+	requests = amqpChannel
 
 	err = amqpChannel.Qos(
 		1,
@@ -70,12 +110,43 @@ func InitClient(amqpConnection *amqp.Connection) error {
 
 // CallRpc performs the RPC call for the specific service.
 func (c *Client) CallRpc(methodName string, args interface{}) (*Response, error) {
+	return c.CallRpcWithContext(methodName, args, nil, nil)
+}
+
+// CallRpcWithContext performs the RPC call with keyword arguments and Nameko
+// context data, which is sent as "nameko.<key>" headers. The call's ID is
+// appended to the "call_id_stack" entry, keeping the last
+// Options.ParentCallsTracked parent IDs. Remote exceptions are returned as
+// *RemoteError.
+func (c *Client) CallRpcWithContext(methodName string, args interface{}, kwargs map[string]interface{}, contextData map[string]interface{}) (*Response, error) {
+	return c.Call(context.Background(), methodName, args, kwargs, contextData)
+}
+
+// Call is CallRpcWithContext giving up on the reply once ctx is done, in which
+// case ctx.Err() is returned. A reply arriving later is discarded.
+func (c *Client) Call(ctx context.Context, methodName string, args interface{}, kwargs map[string]interface{}, contextData map[string]interface{}) (response *Response, err error) {
+	start := time.Now()
+	inFlight := metrics.RPCClientInFlight.WithLabelValues(c.targetService)
+	inFlight.Inc()
+	defer func() {
+		inFlight.Dec()
+		metrics.RPCClientRequests.WithLabelValues(c.targetService, methodName, callResult(err)).Inc()
+		metrics.RPCClientDuration.WithLabelValues(c.targetService, methodName).Observe(time.Since(start).Seconds())
+	}()
+
 	correlationID := uuid.New().String()
 	routingKey := fmt.Sprintf("%s.%s", c.targetService, methodName)
 
+	if args == nil {
+		args = []interface{}{}
+	}
+	if kwargs == nil {
+		kwargs = map[string]interface{}{}
+	}
+
 	payload := map[string]interface{}{
 		"args":   args,
-		"kwargs": map[string]interface{}{},
+		"kwargs": kwargs,
 	}
 	body, err := json.Marshal(payload)
 	if err != nil {
@@ -89,8 +160,8 @@ func (c *Client) CallRpc(methodName string, args interface{}) (*Response, error)
 	defer pendingReplies.Delete(correlationID)
 
 	// Publish the RPC request
-	err = amqpChannel.Publish(
-		Cfg.ExchangeName,
+	err = requests.Publish(
+		clientOptions.ExchangeName,
 		routingKey,
 		false,
 		false,
@@ -98,6 +169,7 @@ func (c *Client) CallRpc(methodName string, args interface{}) (*Response, error)
 			ContentType:   "application/json",
 			CorrelationId: correlationID,
 			ReplyTo:       replyQueueID,
+			Headers:       contextHeaders(withCallID(contextData, correlationID)),
 			Body:          body,
 		},
 	)
@@ -109,32 +181,75 @@ func (c *Client) CallRpc(methodName string, args interface{}) (*Response, error)
 	// Wait for the reply
 	select {
 	case msg := <-replyChan:
-		var response Response
-		if err = json.Unmarshal(msg.Body, &response); err != nil {
+		var reply Response
+		if err = json.Unmarshal(msg.Body, &reply); err != nil {
 			log.Printf("failed to decode response: %v", err)
 			return nil, err
 		}
 
-		if response.Error != nil {
-			// Convert the error to a Go error type
-			return nil, fmt.Errorf(
-				"RPC Error: %s (type: %s, path: %s, args: %v, kwargs: %v)",
-				response.Error.Value,
-				response.Error.ExcType,
-				response.Error.ExcPath,
-				response.Error.ExcArgs,
-				response.Error.ExcKwargs,
-			)
+		if reply.Error != nil {
+			return nil, reply.Error
 		}
 
-		return &Response{Result: response.Result}, nil
+		return &Response{Result: reply.Result}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// callResult labels the outcome of a call: "ok", the remote exc_type, or
+// "error" for failures that are not remote exceptions.
+func callResult(err error) string {
+	var remoteErr *RemoteError
+	if errors.As(err, &remoteErr) && remoteErr.ExcType != "" {
+		return remoteErr.ExcType
+	}
+	return metrics.Result(err)
+}
+
+// withCallID returns a copy of contextData with the call's ID appended to its
+// call_id_stack, truncated to the tracked number of parent calls.
+func withCallID(contextData map[string]interface{}, correlationID string) map[string]interface{} {
+	var stack []interface{}
+	switch parents := contextData[CallIDStackKey].(type) {
+	case []interface{}:
+		stack = append(stack, parents...)
+	case []string:
+		for _, parent := range parents {
+			stack = append(stack, parent)
+		}
+	}
+	if len(stack) > clientOptions.ParentCallsTracked {
+		stack = stack[len(stack)-clientOptions.ParentCallsTracked:]
+	}
+	// Formatted like the IDs of Nameko's standalone RPC proxy.
+	stack = append(stack, fmt.Sprintf("%s.call.%s", clientOptions.ServiceName, correlationID))
+
+	result := make(map[string]interface{}, len(contextData)+1)
+	for key, value := range contextData {
+		result[key] = value
+	}
+	result[CallIDStackKey] = stack
+	return result
+}
+
+// contextHeaders converts Nameko context data into AMQP headers.
+func contextHeaders(contextData map[string]interface{}) amqp.Table {
+	if len(contextData) == 0 {
+		return nil
+	}
+
+	headers := amqp.Table{}
+	for key, value := range contextData {
+		headers[ContextHeaderPrefix+key] = value
+	}
+	return headers
+}
+
 // Sets up the reply queue for receiving RPC responses.
 func setupReplyQueue() error {
 	replyQueueID = uuid.New().String()
-	replyQueueName = fmt.Sprintf(RpcReplyQueueTemplate, Cfg.ServiceName, replyQueueID)
+	replyQueueName = fmt.Sprintf(RpcReplyQueueTemplate, clientOptions.ServiceName, replyQueueID)
 
 	replyQueue, err := amqpChannel.QueueDeclare(
 		replyQueueName,
@@ -152,7 +267,7 @@ func setupReplyQueue() error {
 	err = amqpChannel.QueueBind(
 		replyQueue.Name,
 		replyQueueID,
-		Cfg.ExchangeName,
+		clientOptions.ExchangeName,
 		false,
 		nil,
 	)
@@ -180,6 +295,9 @@ func consumeReplies() {
 		return
 	}
 
+	consuming.Store(true)
+	defer consuming.Store(false)
+
 	for msg := range messages {
 		if ch, ok := pendingReplies.Load(msg.CorrelationId); ok {
 			ch.(chan amqp.Delivery) <- msg
@@ -190,3 +308,9 @@ func consumeReplies() {
 		}
 	}
 }
+
+// ClientReady reports whether the reply queue is bound and replies are being
+// consumed, i.e. whether RPC calls can currently receive responses.
+func ClientReady() bool {
+	return consuming.Load() && amqpChannel != nil && !amqpChannel.IsClosed()
+}
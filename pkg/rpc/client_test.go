@@ -0,0 +1,56 @@
+package rpc
+
+import (
+	amqp "github.com/rabbitmq/amqp091-go"
+	"reflect"
+	"testing"
+)
+
+// replyingPublisher records published requests and answers each one with
+// reply through the pending reply channel.
+type replyingPublisher struct {
+	published []amqp.Publishing
+	reply     string
+}
+
+func (p *replyingPublisher) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	p.published = append(p.published, msg)
+	if ch, ok := pendingReplies.Load(msg.CorrelationId); ok {
+		ch.(chan amqp.Delivery) <- amqp.Delivery{CorrelationId: msg.CorrelationId, Body: []byte(p.reply)}
+	}
+	return nil
+}
+
+func TestCallRpcWithContextHeaders(t *testing.T) {
+	fake := &replyingPublisher{reply: `{"result": 6, "error": null}`}
+	requests = fake
+	clientOptions = Options{ServiceName: "gateway", ParentCallsTracked: 1}.withDefaults()
+	defer func() {
+		requests = nil
+		clientOptions = Options{}
+	}()
+
+	response, err := NewClient("calculator").CallRpcWithContext("multiply", []int{2, 3}, nil, map[string]interface{}{
+		"user_id":      "42",
+		CallIDStackKey: []string{"web.a.1", "web.b.2"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Result != float64(6) {
+		t.Errorf("expected result 6, got %v", response.Result)
+	}
+
+	if len(fake.published) != 1 {
+		t.Fatalf("expected one published request, got %d", len(fake.published))
+	}
+	msg := fake.published[0]
+
+	if userID := msg.Headers[ContextHeaderPrefix+"user_id"]; userID != "42" {
+		t.Errorf("expected user_id header 42, got %v", userID)
+	}
+	expectedStack := []interface{}{"web.b.2", "gateway.call." + msg.CorrelationId}
+	if stack := msg.Headers[ContextHeaderPrefix+CallIDStackKey]; !reflect.DeepEqual(stack, expectedStack) {
+		t.Errorf("expected call_id_stack %v, got %v", expectedStack, stack)
+	}
+}
@@ -1,7 +1,5 @@
 package rpc
 
-import "github.com/joejoe-am/namego/configs"
-
 const (
 	RpcQueueTemplate                       = "rpc-%s"
 	RpcReplyQueueTemplate                  = "rpc.reply-%s-%s"
@@ -9,10 +7,6 @@ const (
 	EventHandlerBroadCaseQueueTemplate     = "evt-%s-%s--%s.%s-%s"
 	EventHandlerSingletonCaseQueueTemplate = "evt-%s-%s"
 	EventHandlerServicePoolQueueTemplate   = "evt-%s-%s--%s.%s"
+	ContextHeaderPrefix                    = "nameko."
+	CallIDStackKey                         = "call_id_stack"
 )
-
-var Cfg *configs.Configs
-
-func init() {
-	Cfg = configs.GetConfigs()
-}
@@ -1,12 +1,17 @@
 package events
 
 import (
+	"github.com/joejoe-am/namego/pkg/metrics"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"log"
 )
 
 // Dispatch sends an event with the given type and payload.
-func Dispatch(conn *amqp.Connection, sourceService string, eventType string, payload []byte) error {
+func Dispatch(conn *amqp.Connection, sourceService string, eventType string, payload []byte) (err error) {
+	defer func() {
+		metrics.EventsDispatched.WithLabelValues(sourceService, eventType, metrics.Result(err)).Inc()
+	}()
+
 	exchangeName := sourceService + ".events"
 
 	ch, err := conn.Channel()
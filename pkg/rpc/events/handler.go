@@ -1,42 +1,83 @@
 package events
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/joejoe-am/namego/pkg/metrics"
 	"github.com/joejoe-am/namego/pkg/rpc"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"log"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type HandlerType string
 type EventHandlerType func(body []byte) error
 
+// TypedEventHandlerType also receives the event type (routing key) of the
+// delivery, which is useful when one handler subscribes to several types.
+type TypedEventHandlerType func(eventType string, body []byte) error
+
 const (
 	ServicePool HandlerType = "SERVICE_POOL"
 	Singleton   HandlerType = "SINGLETON"
 	Broadcast   HandlerType = "BROADCAST"
 )
 
+// DefaultMaxWorkers mirrors Nameko's default max_workers.
+const DefaultMaxWorkers = 10
+
+// MaxQueueNameLength is the longest queue name RabbitMQ accepts (a shortstr).
+const MaxQueueNameLength = 255
+
 type EventConfig struct {
-	SourceService    string
-	EventType        string
-	HandlerType      HandlerType
-	ReliableDelivery bool
-	RequeueOnError   bool
-	BroadcastID      string // Used for Broadcast queues
-	HandlerFunction  EventHandlerType
+	ServiceName          string // Name of the consuming service, required for ServicePool and Broadcast handlers
+	SourceService        string
+	EventType            string
+	EventTypes           []string // Additional event types or topic patterns ("order.*", "#") bound to the same queue
+	UnbindEventTypes     []string // Event types removed from EventTypes, unbound from the existing queue on Start
+	HandlerType          HandlerType
+	ReliableDelivery     bool
+	RequeueOnError       bool
+	BroadcastID          string // Used for Broadcast queues
+	HandlerFunction      EventHandlerType
+	TypedHandlerFunction TypedEventHandlerType // Used instead of HandlerFunction when set
+	MethodName           string                // Method part of queue names, defaults to the handler's short function name
+	MaxWorkers           int                   // Concurrent handler goroutines, defaults to DefaultMaxWorkers
+	Prefetch             int                   // Channel QoS prefetch count, defaults to MaxWorkers
+	Ordered              bool                  // Process events one at a time in delivery order
+	Retry                *RetryPolicy          // Delayed redelivery of failed events, replaces RequeueOnError requeueing
 }
 
 type EventHandler struct {
 	config     EventConfig
 	queueName  string
+	legacyName string // Queue name derived from the Go runtime function name, if different
+	eventTypes []string
 	exclusive  bool
 	autoDelete bool
 	queue      *amqp.Queue
 	handlers   map[string]func(body []byte) error // Map of event handlers
+
+	mu          sync.Mutex
+	channel     *amqp.Channel
+	consumerTag string
+	cancel      context.CancelFunc
+	done        chan struct{} // Closed once Start has returned
+	started     chan struct{} // Closed once Start consumes for the first time
+	startOnce   sync.Once
+	inFlight    sync.WaitGroup
+	consuming   atomic.Bool
+
+	retryMu sync.Mutex       // Serializes retry publishes, see publishRetry
+	returns chan amqp.Return // Retry publishes returned as unroutable
 }
 
-func generateQueueName(eventCfg EventConfig) (string, bool, bool) {
+func generateQueueName(eventCfg EventConfig, eventType string) (string, bool, bool) {
 	var queueName string
 	exclusive := false
 	autoDelete := !eventCfg.ReliableDelivery
@@ -46,26 +87,23 @@ func generateQueueName(eventCfg EventConfig) (string, bool, bool) {
 		queueName = fmt.Sprintf(
 			rpc.EventHandlerServicePoolQueueTemplate,
 			eventCfg.SourceService,
-			eventCfg.EventType,
-			rpc.Cfg.ServiceName,
-			getFunctionName(eventCfg.HandlerFunction),
+			eventType,
+			eventCfg.ServiceName,
+			eventCfg.MethodName,
 		)
 	case Singleton:
 		queueName = fmt.Sprintf(
 			rpc.EventHandlerSingletonCaseQueueTemplate,
 			eventCfg.SourceService,
-			eventCfg.EventType,
+			eventType,
 		)
 	case Broadcast:
-		if eventCfg.BroadcastID == "" {
-			eventCfg.BroadcastID = uuid.New().String()
-		}
 		queueName = fmt.Sprintf(
 			rpc.EventHandlerBroadCaseQueueTemplate,
 			eventCfg.SourceService,
-			eventCfg.EventType,
-			rpc.Cfg.ServiceName,
-			getFunctionName(eventCfg.HandlerFunction),
+			eventType,
+			eventCfg.ServiceName,
+			eventCfg.MethodName,
 			eventCfg.BroadcastID,
 		)
 		exclusive = !eventCfg.ReliableDelivery
@@ -76,34 +114,189 @@ func generateQueueName(eventCfg EventConfig) (string, bool, bool) {
 
 // NewEventHandler initializes a new event handler.
 func NewEventHandler(cfg EventConfig) (*EventHandler, error) {
-	queueName, exclusive, autoDelete := generateQueueName(cfg)
+	if cfg.MaxWorkers < 0 || cfg.Prefetch < 0 {
+		return nil, fmt.Errorf("invalid event config: MaxWorkers and Prefetch must not be negative")
+	}
+
+	if cfg.Ordered {
+		cfg.MaxWorkers = 1
+	} else if cfg.MaxWorkers == 0 {
+		cfg.MaxWorkers = DefaultMaxWorkers
+	}
+
+	if cfg.Prefetch == 0 {
+		cfg.Prefetch = cfg.MaxWorkers
+	}
+
+	if cfg.Retry != nil {
+		retry := cfg.Retry.withDefaults()
+		cfg.Retry = &retry
+	}
+
+	if cfg.ServiceName == "" && cfg.HandlerType != Singleton {
+		return nil, fmt.Errorf("invalid event config: ServiceName is required for %s handlers", cfg.HandlerType)
+	}
+
+	if cfg.HandlerFunction == nil && cfg.TypedHandlerFunction == nil {
+		return nil, fmt.Errorf("invalid event config: HandlerFunction or TypedHandlerFunction is required")
+	}
+
+	eventTypes, err := collectEventTypes(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	queueEventType, err := queueEventType(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, eventType := range cfg.UnbindEventTypes {
+		if slices.Contains(eventTypes, eventType) {
+			return nil, fmt.Errorf("invalid event config: event type %q is both bound and unbound", eventType)
+		}
+	}
+
+	if cfg.HandlerType == Broadcast && cfg.BroadcastID == "" {
+		cfg.BroadcastID = uuid.New().String()
+	}
+
+	runtimeName := getFunctionName(cfg.handlerFunction())
+	if cfg.MethodName == "" {
+		cfg.MethodName = shortFunctionName(runtimeName)
+	}
+	if cfg.MethodName == "" && cfg.HandlerType != Singleton {
+		return nil, fmt.Errorf("invalid event config: MethodName is required for anonymous handler functions")
+	}
+
+	queueName, exclusive, autoDelete := generateQueueName(cfg, queueEventType)
+	if len(queueName) > MaxQueueNameLength {
+		return nil, fmt.Errorf("invalid event config: queue name %q exceeds %d bytes", queueName, MaxQueueNameLength)
+	}
+	if cfg.Retry != nil {
+		for _, name := range cfg.Retry.queueNames(queueName) {
+			if len(name) > MaxQueueNameLength {
+				return nil, fmt.Errorf("invalid event config: retry queue name %q exceeds %d bytes", name, MaxQueueNameLength)
+			}
+		}
+	}
+
+	legacyCfg := cfg
+	legacyCfg.MethodName = runtimeName
+	legacyName, _, _ := generateQueueName(legacyCfg, queueEventType)
+	if legacyName == queueName || runtimeName == "" {
+		legacyName = ""
+	}
 
 	return &EventHandler{
 		config:     cfg,
 		queueName:  queueName,
+		legacyName: legacyName,
+		eventTypes: eventTypes,
 		exclusive:  exclusive,
 		autoDelete: autoDelete,
 		handlers:   make(map[string]func(body []byte) error),
+		started:    make(chan struct{}),
 	}, nil
 }
 
-func (h *EventHandler) Start(conn *amqp.Connection) error {
+// WithOptions returns cfg with ServiceName, MaxWorkers and Prefetch taken
+// from service-wide RPC options where cfg leaves them unset, so max_workers
+// and PREFETCH_COUNT apply to event handlers as they do in Nameko.
+func (cfg EventConfig) WithOptions(options rpc.Options) EventConfig {
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = options.ServiceName
+	}
+	if cfg.MaxWorkers == 0 {
+		cfg.MaxWorkers = options.MaxWorkers
+	}
+	if cfg.Prefetch == 0 {
+		cfg.Prefetch = options.PrefetchCount
+	}
+	return cfg
+}
+
+// handlerFunction returns whichever handler function is configured.
+func (cfg EventConfig) handlerFunction() interface{} {
+	if cfg.TypedHandlerFunction != nil {
+		return cfg.TypedHandlerFunction
+	}
+	return cfg.HandlerFunction
+}
+
+// warnLegacyQueue logs a warning if a queue named after the Go runtime function
+// name still exists, since its events are no longer consumed. A passive declare
+// closes the channel when the queue is missing, so a throwaway channel is used.
+func (h *EventHandler) warnLegacyQueue(conn *amqp.Connection) {
+	if h.legacyName == "" || !h.config.ReliableDelivery {
+		return
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return
+	}
+	defer ch.Close()
+
+	queue, err := ch.QueueDeclarePassive(h.legacyName, true, h.autoDelete, h.exclusive, false, nil)
+	if err != nil {
+		return
+	}
+
+	log.Printf(
+		"legacy event queue %s still exists with %d messages; set MethodName to %q to keep consuming it, or drain and delete it",
+		h.legacyName, queue.Messages, getFunctionName(h.config.handlerFunction()),
+	)
+}
+
+// Start declares the handler queue and consumes events until ctx is cancelled,
+// Stop is called or the delivery channel is closed. In-flight handlers are
+// drained before the channel is closed; unacked deliveries are requeued.
+func (h *EventHandler) Start(ctx context.Context, conn *amqp.Connection) error {
 	ch, err := conn.Channel()
 	if err != nil {
 		log.Printf("failed to open RabbitMQ channel: %v", err)
 		return err
 	}
 
+	h.warnLegacyQueue(conn)
+
 	err = h.SetupQueue(ch)
 
 	if err != nil {
+		_ = ch.Close()
 		return err
 	}
 
+	var returns chan amqp.Return
+	if h.config.Retry != nil {
+		// Failed events are only acked once their retry publish is confirmed.
+		if err := ch.Confirm(false); err != nil {
+			log.Printf("failed to enable publisher confirms: %v", err)
+			_ = ch.Close()
+			return err
+		}
+		returns = ch.NotifyReturn(make(chan amqp.Return, 1))
+	}
+
+	err = ch.Qos(
+		h.config.Prefetch,
+		0,
+		false,
+	)
+	if err != nil {
+		log.Printf("error setting qos: %v", err)
+		_ = ch.Close()
+		return err
+	}
+
+	consumerTag := uuid.New().String()
+
+	// Deliveries are always acked manually so the prefetch limit applies.
 	msgs, err := ch.Consume(
 		h.queueName,
-		"",
-		!h.config.RequeueOnError,
+		consumerTag,
+		false,
 		false,
 		false,
 		false,
@@ -111,16 +304,120 @@ func (h *EventHandler) Start(conn *amqp.Connection) error {
 	)
 	if err != nil {
 		log.Printf("failed to consume RabbitMQ events: %v", err)
+		_ = ch.Close()
 		return err
 	}
 
-	for msg := range msgs {
-		go h.handleMessage(msg)
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	h.mu.Lock()
+	h.channel = ch
+	h.returns = returns
+	h.consumerTag = consumerTag
+	h.cancel = cancel
+	h.done = make(chan struct{})
+	done := h.done
+	h.mu.Unlock()
+
+	defer close(done)
+
+	h.consuming.Store(true)
+	h.startOnce.Do(func() { close(h.started) })
+	h.consume(runCtx, msgs)
+	h.consuming.Store(false)
+
+	// Stop the broker from pushing more deliveries, finish the ones already
+	// being handled, then close the channel so prefetched ones are requeued.
+	if err := ch.Cancel(consumerTag, false); err != nil && !ch.IsClosed() {
+		log.Printf("failed to cancel consumer: %v", err)
+	}
+
+	h.inFlight.Wait()
+
+	if err := ch.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+		log.Printf("failed to close channel: %v", err)
 	}
 
 	return nil
 }
 
+// Stop cancels the consumer and waits for in-flight handlers to finish. If ctx
+// expires first the channel is closed immediately, so RabbitMQ requeues every
+// unacked delivery, and ctx's error is returned.
+func (h *EventHandler) Stop(ctx context.Context) error {
+	h.mu.Lock()
+	ch, cancel, done := h.channel, h.cancel, h.done
+	h.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		if err := ch.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+			log.Printf("failed to close channel: %v", err)
+		}
+		return ctx.Err()
+	}
+}
+
+// Started returns a channel that is closed once Start has declared the queue
+// and started consuming. Start returns an error instead if it cannot.
+func (h *EventHandler) Started() <-chan struct{} {
+	return h.started
+}
+
+// IsConsuming reports whether the handler is consuming events. It turns false
+// when the handler is stopped or its delivery channel closes.
+func (h *EventHandler) IsConsuming() bool {
+	h.mu.Lock()
+	ch := h.channel
+	h.mu.Unlock()
+
+	return h.consuming.Load() && ch != nil && !ch.IsClosed()
+}
+
+// consume dispatches deliveries to the handler until ctx is done or msgs is closed.
+func (h *EventHandler) consume(ctx context.Context, msgs <-chan amqp.Delivery) {
+	workerPool := make(chan struct{}, h.config.MaxWorkers)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+
+			if h.config.Ordered {
+				h.handleMessage(msg)
+				continue
+			}
+
+			select {
+			case workerPool <- struct{}{}:
+			case <-ctx.Done():
+				// Not yet handled; left unacked so it is requeued on close.
+				return
+			}
+
+			h.inFlight.Add(1)
+			go func(m amqp.Delivery) {
+				defer h.inFlight.Done()
+				defer func() { <-workerPool }()
+				h.handleMessage(m)
+			}(msg)
+		}
+	}
+}
+
 func (h *EventHandler) SetupQueue(ch *amqp.Channel) error {
 	exchange := fmt.Sprintf("%s.events", h.config.SourceService)
 
@@ -153,30 +450,65 @@ func (h *EventHandler) SetupQueue(ch *amqp.Channel) error {
 
 	h.queue = &queue
 
-	err = ch.QueueBind(
-		h.queueName,
-		h.config.EventType,
-		exchange,
-		false,
-		nil,
-	)
-	if err != nil {
-		log.Printf("failed to bind queue: %v", err)
-		return err
+	for _, eventType := range h.eventTypes {
+		err = ch.QueueBind(
+			h.queueName,
+			eventType,
+			exchange,
+			false,
+			nil,
+		)
+		if err != nil {
+			log.Printf("failed to bind queue to %s: %v", eventType, err)
+			return err
+		}
+	}
+
+	for _, eventType := range h.config.UnbindEventTypes {
+		err = ch.QueueUnbind(
+			h.queueName,
+			eventType,
+			exchange,
+			nil,
+		)
+		if err != nil {
+			log.Printf("failed to unbind queue from %s: %v", eventType, err)
+			return err
+		}
+	}
+
+	if h.config.Retry != nil {
+		return h.setupRetryQueues(ch)
 	}
 
 	return nil
 }
 
 func (h *EventHandler) handleMessage(msg amqp.Delivery) {
-	handler := h.config.HandlerFunction
+	eventType := deliveryEventType(msg)
+	start := time.Now()
+
+	var err error
+	if h.config.TypedHandlerFunction != nil {
+		err = h.config.TypedHandlerFunction(eventType, msg.Body)
+	} else {
+		err = h.config.HandlerFunction(msg.Body)
+	}
 
-	err := handler(msg.Body)
+	metrics.EventsHandlerDuration.WithLabelValues(h.queueName).Observe(time.Since(start).Seconds())
+
+	outcome := "ack"
 	if err != nil {
 		log.Printf("handler error: %v\n", err)
-		_ = msg.Nack(false, h.config.RequeueOnError)
-		return
+		if h.config.Retry != nil {
+			outcome = h.retry(msg, err)
+		} else {
+			outcome = "nack"
+			_ = msg.Nack(false, h.config.RequeueOnError)
+		}
+	} else {
+		_ = msg.Ack(false)
 	}
 
-	_ = msg.Ack(false)
+	metrics.EventsConsumed.WithLabelValues(h.queueName, eventType, outcome).Inc()
 }
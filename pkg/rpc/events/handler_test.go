@@ -0,0 +1,366 @@
+package events
+
+import (
+	"context"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func handleOrder(body []byte) error { return nil }
+
+func TestNewEventHandlerQueueName(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        EventConfig
+		expectedQueue string
+		expectError   bool
+	}{
+		{
+			name: "Service pool",
+			config: EventConfig{
+				ServiceName:     "billing",
+				SourceService:   "orders",
+				EventType:       "ORDER_CREATED",
+				HandlerType:     ServicePool,
+				HandlerFunction: handleOrder,
+			},
+			expectedQueue: "evt-orders-ORDER_CREATED--billing.handleOrder",
+		},
+		{
+			name: "Broadcast",
+			config: EventConfig{
+				ServiceName:     "billing",
+				SourceService:   "orders",
+				EventType:       "ORDER_CREATED",
+				HandlerType:     Broadcast,
+				BroadcastID:     "node-1",
+				HandlerFunction: handleOrder,
+				MethodName:      "handle_order",
+			},
+			expectedQueue: "evt-orders-ORDER_CREATED--billing.handle_order-node-1",
+		},
+		{
+			name: "Singleton without service name",
+			config: EventConfig{
+				SourceService:   "orders",
+				EventType:       "ORDER_CREATED",
+				HandlerType:     Singleton,
+				HandlerFunction: handleOrder,
+			},
+			expectedQueue: "evt-orders-ORDER_CREATED",
+		},
+		{
+			name: "Named after EventType only",
+			config: EventConfig{
+				ServiceName:     "billing",
+				SourceService:   "orders",
+				EventType:       "ORDER_CREATED",
+				EventTypes:      []string{"ORDER_PAID", "order.*"},
+				HandlerType:     ServicePool,
+				HandlerFunction: handleOrder,
+			},
+			expectedQueue: "evt-orders-ORDER_CREATED--billing.handleOrder",
+		},
+		{
+			name: "Single EventTypes entry",
+			config: EventConfig{
+				ServiceName:     "billing",
+				SourceService:   "orders",
+				EventTypes:      []string{"order.*"},
+				HandlerType:     ServicePool,
+				HandlerFunction: handleOrder,
+			},
+			expectedQueue: "evt-orders-order.*--billing.handleOrder",
+		},
+		{
+			name: "Several EventTypes without EventType",
+			config: EventConfig{
+				ServiceName:     "billing",
+				SourceService:   "orders",
+				EventTypes:      []string{"ORDER_CREATED", "ORDER_PAID"},
+				HandlerType:     ServicePool,
+				HandlerFunction: handleOrder,
+			},
+			expectError: true,
+		},
+		{
+			name: "Event type both bound and unbound",
+			config: EventConfig{
+				ServiceName:      "billing",
+				SourceService:    "orders",
+				EventType:        "ORDER_CREATED",
+				EventTypes:       []string{"ORDER_PAID"},
+				UnbindEventTypes: []string{"ORDER_PAID"},
+				HandlerType:      ServicePool,
+				HandlerFunction:  handleOrder,
+			},
+			expectError: true,
+		},
+		{
+			name: "Retry queue name too long",
+			config: EventConfig{
+				ServiceName:     strings.Repeat("s", 212),
+				SourceService:   "orders",
+				EventType:       "ORDER_CREATED",
+				HandlerType:     ServicePool,
+				HandlerFunction: handleOrder,
+				Retry:           &RetryPolicy{},
+			},
+			expectError: true,
+		},
+		{
+			name: "Service pool without service name",
+			config: EventConfig{
+				SourceService:   "orders",
+				EventType:       "ORDER_CREATED",
+				HandlerType:     ServicePool,
+				HandlerFunction: handleOrder,
+			},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler, err := NewEventHandler(test.config)
+
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected error, got queue %q", handler.queueName)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if handler.queueName != test.expectedQueue {
+				t.Errorf("expected queue %q, got %q", test.expectedQueue, handler.queueName)
+			}
+		})
+	}
+}
+
+func TestNewEventHandlerWorkers(t *testing.T) {
+	tests := []struct {
+		name               string
+		maxWorkers         int
+		prefetch           int
+		ordered            bool
+		expectedMaxWorkers int
+		expectedPrefetch   int
+		expectError        bool
+	}{
+		{name: "Defaults", expectedMaxWorkers: DefaultMaxWorkers, expectedPrefetch: DefaultMaxWorkers},
+		{name: "Prefetch follows MaxWorkers", maxWorkers: 4, expectedMaxWorkers: 4, expectedPrefetch: 4},
+		{name: "Explicit prefetch", maxWorkers: 4, prefetch: 20, expectedMaxWorkers: 4, expectedPrefetch: 20},
+		{name: "Ordered", maxWorkers: 4, ordered: true, expectedMaxWorkers: 1, expectedPrefetch: 1},
+		{name: "Ordered with prefetch", ordered: true, prefetch: 5, expectedMaxWorkers: 1, expectedPrefetch: 5},
+		{name: "Negative MaxWorkers", maxWorkers: -1, expectError: true},
+		{name: "Negative Prefetch", prefetch: -1, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler, err := NewEventHandler(EventConfig{
+				ServiceName:     "billing",
+				SourceService:   "orders",
+				EventType:       "ORDER_CREATED",
+				HandlerType:     ServicePool,
+				HandlerFunction: handleOrder,
+				MaxWorkers:      test.maxWorkers,
+				Prefetch:        test.prefetch,
+				Ordered:         test.ordered,
+			})
+
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected error, got MaxWorkers %d and Prefetch %d", handler.config.MaxWorkers, handler.config.Prefetch)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if handler.config.MaxWorkers != test.expectedMaxWorkers || handler.config.Prefetch != test.expectedPrefetch {
+				t.Errorf("expected MaxWorkers %d and Prefetch %d, got %d and %d",
+					test.expectedMaxWorkers, test.expectedPrefetch, handler.config.MaxWorkers, handler.config.Prefetch)
+			}
+		})
+	}
+}
+
+// fakeAcknowledger records the delivery tags acked and nacked by a handler.
+type fakeAcknowledger struct {
+	mu     sync.Mutex
+	acked  []uint64
+	nacked []uint64
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.acked = append(a.acked, tag)
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nacked = append(a.nacked, tag)
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func (a *fakeAcknowledger) ackedTags() []uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]uint64(nil), a.acked...)
+}
+
+func (a *fakeAcknowledger) nackedTags() []uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]uint64(nil), a.nacked...)
+}
+
+// deliveries returns a closed channel holding count deliveries tagged 1 to count.
+func deliveries(acknowledger amqp.Acknowledger, count int) <-chan amqp.Delivery {
+	msgs := make(chan amqp.Delivery, count)
+	for tag := 1; tag <= count; tag++ {
+		msgs <- amqp.Delivery{Acknowledger: acknowledger, DeliveryTag: uint64(tag), RoutingKey: "ORDER_CREATED"}
+	}
+	close(msgs)
+	return msgs
+}
+
+func TestConsumeWorkers(t *testing.T) {
+	tests := []struct {
+		name                string
+		maxWorkers          int
+		ordered             bool
+		expectedConcurrency int32
+	}{
+		{name: "Worker pool", maxWorkers: 3, expectedConcurrency: 3},
+		{name: "Ordered", maxWorkers: 3, ordered: true, expectedConcurrency: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var running, concurrency atomic.Int32
+			handler, err := NewEventHandler(EventConfig{
+				ServiceName:   "billing",
+				SourceService: "orders",
+				EventType:     "ORDER_CREATED",
+				HandlerType:   ServicePool,
+				MethodName:    "handle_order",
+				MaxWorkers:    test.maxWorkers,
+				Ordered:       test.ordered,
+				HandlerFunction: func(body []byte) error {
+					current := running.Add(1)
+					defer running.Add(-1)
+					for {
+						highest := concurrency.Load()
+						if current <= highest || concurrency.CompareAndSwap(highest, current) {
+							break
+						}
+					}
+					time.Sleep(10 * time.Millisecond)
+					return nil
+				},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			acknowledger := &fakeAcknowledger{}
+			handler.consume(context.Background(), deliveries(acknowledger, 9))
+			handler.inFlight.Wait()
+
+			if highest := concurrency.Load(); highest != test.expectedConcurrency {
+				t.Errorf("expected %d concurrent handlers, got %d", test.expectedConcurrency, highest)
+			}
+			acked := acknowledger.ackedTags()
+			if len(acked) != 9 {
+				t.Fatalf("expected 9 acked deliveries, got %v", acked)
+			}
+			if test.ordered {
+				for i, tag := range acked {
+					if tag != uint64(i+1) {
+						t.Errorf("expected deliveries acked in order, got %v", acked)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestStopDrainsInFlightEvents(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	handler, err := NewEventHandler(EventConfig{
+		ServiceName:   "billing",
+		SourceService: "orders",
+		EventType:     "ORDER_CREATED",
+		HandlerType:   ServicePool,
+		MethodName:    "handle_order",
+		MaxWorkers:    1,
+		HandlerFunction: func(body []byte) error {
+			started <- struct{}{}
+			<-release
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := handler.Stop(context.Background()); err != nil {
+		t.Errorf("expected Stop before Start to return nil, got %v", err)
+	}
+
+	acknowledger := &fakeAcknowledger{}
+	msgs := make(chan amqp.Delivery, 2)
+	msgs <- amqp.Delivery{Acknowledger: acknowledger, DeliveryTag: 1}
+	msgs <- amqp.Delivery{Acknowledger: acknowledger, DeliveryTag: 2}
+
+	// Run consume the way Start does, without a broker channel.
+	ctx, cancel := context.WithCancel(context.Background())
+	handler.cancel, handler.done = cancel, make(chan struct{})
+	go func() {
+		handler.consume(ctx, msgs)
+		handler.inFlight.Wait()
+		close(handler.done)
+	}()
+	<-started
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- handler.Stop(context.Background()) }()
+
+	select {
+	case err := <-stopped:
+		t.Fatalf("expected Stop to wait for the in-flight event, returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Errorf("unexpected stop error: %v", err)
+	}
+
+	if acked := acknowledger.ackedTags(); len(acked) != 1 || acked[0] != 1 {
+		t.Errorf("expected only the in-flight delivery to be acked, got %v", acked)
+	}
+	if nacked := acknowledger.nackedTags(); len(nacked) != 0 {
+		t.Errorf("expected the waiting delivery to be left for requeueing, got nacks %v", nacked)
+	}
+}
@@ -0,0 +1,237 @@
+package events
+
+import (
+	"fmt"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"log"
+	"time"
+)
+
+const (
+	RetryQueueTemplate       = "%s.retry-%d" // handler queue, delay in ms
+	ParkingLotQueueTemplate  = "%s.parking-lot"
+	AttemptHeader            = "x-namego-attempt"
+	EventTypeHeader          = "x-namego-event-type"
+	LastErrorHeader          = "x-namego-last-error"
+	retryQueueExpiryOverhead = time.Minute
+	parkingLotExpiry         = 24 * time.Hour // Unused parking lots of non-reliable handlers are deleted after this
+)
+
+// RetryPolicy configures delayed redelivery of events whose handler failed.
+// Failed events are parked in a per-delay retry queue whose TTL dead-letters
+// them back to the handler queue, and moved to a parking-lot queue once
+// MaxAttempts is reached.
+type RetryPolicy struct {
+	MaxAttempts  int           // Total handler attempts including the first, defaults to 5
+	InitialDelay time.Duration // Delay before the first retry, defaults to 1s
+	MaxDelay     time.Duration // Upper bound for the delay, defaults to 5m
+	Multiplier   float64       // Backoff factor between retries, defaults to 2
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 5
+	}
+	if p.InitialDelay <= 0 {
+		p.InitialDelay = time.Second
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = 5 * time.Minute
+	}
+	if p.MaxDelay < p.InitialDelay {
+		p.MaxDelay = p.InitialDelay
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	return p
+}
+
+// Delay returns how long to wait before retrying after the given failed attempt (1-based).
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := float64(p.InitialDelay)
+	for i := 1; i < attempt; i++ {
+		delay *= p.Multiplier
+		if delay >= float64(p.MaxDelay) {
+			return p.MaxDelay
+		}
+	}
+	return time.Duration(delay)
+}
+
+// delays returns the distinct retry delays used by the policy, in order.
+func (p RetryPolicy) delays() []time.Duration {
+	var delays []time.Duration
+	for attempt := 1; attempt < p.MaxAttempts; attempt++ {
+		delay := p.Delay(attempt)
+		if len(delays) > 0 && delays[len(delays)-1] == delay {
+			continue
+		}
+		delays = append(delays, delay)
+	}
+	return delays
+}
+
+func retryQueueName(queueName string, delay time.Duration) string {
+	return fmt.Sprintf(RetryQueueTemplate, queueName, delay.Milliseconds())
+}
+
+// queueNames returns the retry and parking-lot queue names for a handler queue.
+func (p RetryPolicy) queueNames(queueName string) []string {
+	var names []string
+	for _, delay := range p.delays() {
+		names = append(names, retryQueueName(queueName, delay))
+	}
+	return append(names, fmt.Sprintf(ParkingLotQueueTemplate, queueName))
+}
+
+// setupRetryQueues declares the retry queues and the parking-lot queue for the handler.
+func (h *EventHandler) setupRetryQueues(ch *amqp.Channel) error {
+	for _, delay := range h.config.Retry.delays() {
+		args := amqp.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": h.queueName,
+		}
+		if h.autoDelete {
+			// The handler queue may disappear, so don't leave retry queues behind either.
+			args["x-expires"] = (delay + retryQueueExpiryOverhead).Milliseconds()
+		}
+
+		_, err := ch.QueueDeclare(
+			retryQueueName(h.queueName, delay),
+			true,
+			false,
+			false,
+			false,
+			args,
+		)
+		if err != nil {
+			log.Printf("failed to declare retry queue: %v", err)
+			return err
+		}
+	}
+
+	_, err := ch.QueueDeclare(
+		fmt.Sprintf(ParkingLotQueueTemplate, h.queueName),
+		true,
+		false,
+		false,
+		false,
+		h.parkingLotArgs(),
+	)
+	if err != nil {
+		log.Printf("failed to declare parking-lot queue: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// parkingLotArgs returns the parking-lot queue arguments. Handlers without
+// reliable delivery get a new queue on every restart, so their parking lot
+// expires instead of piling up; parked events stay for parkingLotExpiry.
+func (h *EventHandler) parkingLotArgs() amqp.Table {
+	if !h.autoDelete {
+		return nil
+	}
+	return amqp.Table{"x-expires": parkingLotExpiry.Milliseconds()}
+}
+
+// retry republishes a failed delivery to the retry queue for its attempt, or
+// to the parking-lot queue once the policy is exhausted, and acks the original
+// once the broker confirmed the publish. Otherwise the original is requeued.
+// It returns the outcome recorded in the events consumed metric.
+func (h *EventHandler) retry(msg amqp.Delivery, handlerErr error) string {
+	attempt := deliveryAttempt(msg) + 1
+
+	headers := amqp.Table{}
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+	headers[AttemptHeader] = int32(attempt)
+	headers[EventTypeHeader] = deliveryEventType(msg)
+	headers[LastErrorHeader] = handlerErr.Error()
+
+	routingKey, outcome := "", "retry"
+	if attempt >= h.config.Retry.MaxAttempts {
+		routingKey, outcome = fmt.Sprintf(ParkingLotQueueTemplate, h.queueName), "parked"
+		log.Printf("event parked after %d attempts: %v\n", attempt, handlerErr)
+	} else {
+		routingKey = retryQueueName(h.queueName, h.config.Retry.Delay(attempt))
+	}
+
+	err := h.publishRetry(routingKey, amqp.Publishing{
+		ContentType:   msg.ContentType,
+		CorrelationId: msg.CorrelationId,
+		MessageId:     msg.MessageId,
+		DeliveryMode:  amqp.Persistent,
+		Headers:       headers,
+		Body:          msg.Body,
+	})
+	if err != nil {
+		log.Printf("failed to publish event retry: %v", err)
+		_ = msg.Nack(false, true)
+		return "nack"
+	}
+
+	_ = msg.Ack(false)
+	return outcome
+}
+
+// publishRetry publishes to a retry or parking-lot queue and waits for the
+// broker to confirm it. The publish is mandatory, so a missing queue returns
+// the message instead of dropping it. Publishes are serialized so a returned
+// message belongs to the publish that is waiting; returns are dispatched
+// before the confirm that follows them.
+func (h *EventHandler) publishRetry(queue string, publishing amqp.Publishing) error {
+	h.retryMu.Lock()
+	defer h.retryMu.Unlock()
+
+	confirmation, err := h.channel.PublishWithDeferredConfirm(
+		"",
+		queue,
+		true,
+		false,
+		publishing,
+	)
+	if err != nil {
+		return err
+	}
+
+	if !confirmation.Wait() {
+		return fmt.Errorf("publish to %s was not confirmed", queue)
+	}
+
+	select {
+	case returned, ok := <-h.returns:
+		if ok {
+			return fmt.Errorf("publish to %s was returned: %s", queue, returned.ReplyText)
+		}
+	default:
+	}
+
+	return nil
+}
+
+// deliveryAttempt returns how many times the delivery has already failed.
+func deliveryAttempt(msg amqp.Delivery) int {
+	switch attempt := msg.Headers[AttemptHeader].(type) {
+	case int32:
+		return int(attempt)
+	case int64:
+		return int(attempt)
+	case int:
+		return attempt
+	}
+	return 0
+}
+
+// deliveryEventType returns the original event type, which retried deliveries
+// carry in a header since they arrive with the handler queue as routing key.
+func deliveryEventType(msg amqp.Delivery) string {
+	if eventType, ok := msg.Headers[EventTypeHeader].(string); ok {
+		return eventType
+	}
+	return msg.RoutingKey
+}
@@ -0,0 +1,60 @@
+package events
+
+import (
+	amqp "github.com/rabbitmq/amqp091-go"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts:  6,
+		InitialDelay: time.Second,
+		MaxDelay:     5 * time.Second,
+		Multiplier:   2,
+	}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, delay := range expected {
+		if result := policy.Delay(i + 1); result != delay {
+			t.Errorf("attempt %d: expected %v, got %v", i+1, delay, result)
+		}
+	}
+
+	expectedQueues := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	if result := policy.delays(); !reflect.DeepEqual(result, expectedQueues) {
+		t.Errorf("expected retry queue delays %v, got %v", expectedQueues, result)
+	}
+}
+
+func TestParkingLotArgs(t *testing.T) {
+	tests := []struct {
+		name             string
+		reliableDelivery bool
+		expected         amqp.Table
+	}{
+		{"Reliable delivery", true, nil},
+		{"Non-reliable delivery", false, amqp.Table{"x-expires": parkingLotExpiry.Milliseconds()}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler, err := NewEventHandler(EventConfig{
+				ServiceName:      "billing",
+				SourceService:    "orders",
+				EventType:        "ORDER_CREATED",
+				HandlerType:      Broadcast,
+				ReliableDelivery: test.reliableDelivery,
+				HandlerFunction:  handleOrder,
+				Retry:            &RetryPolicy{},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if args := handler.parkingLotArgs(); !reflect.DeepEqual(args, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, args)
+			}
+		})
+	}
+}
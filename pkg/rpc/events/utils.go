@@ -1,10 +1,81 @@
 package events
 
 import (
+	"fmt"
 	"reflect"
 	"runtime"
+	"strings"
 )
 
 func getFunctionName(i interface{}) string {
 	return runtime.FuncForPC(reflect.ValueOf(i).Pointer()).Name()
 }
+
+// shortFunctionName strips the package path and closure or method value
+// suffixes from a runtime function name, e.g.
+// "github.com/acme/svc/service.(*Handlers).OnOrder-fm" becomes "OnOrder".
+// It returns "" for anonymous functions, which have no stable name.
+func shortFunctionName(name string) string {
+	name = name[strings.LastIndex(name, "/")+1:]
+	name = strings.TrimSuffix(name, "-fm")
+
+	parts := strings.Split(name, ".")
+	if len(parts) < 2 {
+		return ""
+	}
+
+	last := parts[len(parts)-1]
+	if strings.HasPrefix(last, "func") || strings.HasPrefix(last, "gowrap") {
+		return ""
+	}
+
+	return last
+}
+
+// collectEventTypes merges EventType and EventTypes into a de-duplicated list
+// of binding keys, validating each one as an AMQP topic pattern.
+func collectEventTypes(cfg EventConfig) ([]string, error) {
+	seen := make(map[string]bool)
+	var eventTypes []string
+
+	for _, eventType := range append([]string{cfg.EventType}, cfg.EventTypes...) {
+		if eventType == "" || seen[eventType] {
+			continue
+		}
+		if err := validateBindingKey(eventType); err != nil {
+			return nil, err
+		}
+		seen[eventType] = true
+		eventTypes = append(eventTypes, eventType)
+	}
+
+	if len(eventTypes) == 0 {
+		return nil, fmt.Errorf("invalid event config: at least one event type is required")
+	}
+
+	return eventTypes, nil
+}
+
+// validateBindingKey checks that the wildcards "*" and "#" only appear as
+// whole dot-separated words, as required by topic exchanges.
+func validateBindingKey(key string) error {
+	for _, word := range strings.Split(key, ".") {
+		if strings.ContainsAny(word, "*#") && word != "*" && word != "#" {
+			return fmt.Errorf("invalid event type %q: wildcards must be whole words", key)
+		}
+	}
+	return nil
+}
+
+// queueEventType returns the event type part of a queue name. It does not
+// depend on EventTypes, so bindings can be added or removed without renaming
+// the durable queue and orphaning the messages in it.
+func queueEventType(cfg EventConfig) (string, error) {
+	if cfg.EventType != "" {
+		return cfg.EventType, nil
+	}
+	if len(cfg.EventTypes) == 1 {
+		return cfg.EventTypes[0], nil
+	}
+	return "", fmt.Errorf("invalid event config: EventType is required to name the queue of a handler bound to several event types")
+}
@@ -0,0 +1,86 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollectEventTypes(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        EventConfig
+		expectedTypes []string
+		expectError   bool
+	}{
+		{
+			name:          "Single event type",
+			config:        EventConfig{EventType: "ORDER_CREATED"},
+			expectedTypes: []string{"ORDER_CREATED"},
+		},
+		{
+			name: "Merged and de-duplicated",
+			config: EventConfig{
+				EventType:  "ORDER_CREATED",
+				EventTypes: []string{"ORDER_PAID", "ORDER_CREATED"},
+			},
+			expectedTypes: []string{"ORDER_CREATED", "ORDER_PAID"},
+		},
+		{
+			name:          "Wildcards",
+			config:        EventConfig{EventTypes: []string{"order.*", "#"}},
+			expectedTypes: []string{"order.*", "#"},
+		},
+		{
+			name:        "Partial wildcard",
+			config:      EventConfig{EventType: "order*"},
+			expectError: true,
+		},
+		{
+			name:        "No event type",
+			config:      EventConfig{},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := collectEventTypes(test.config)
+
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected error, got %v", result)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expectedTypes) {
+				t.Errorf("expected %v, got %v", test.expectedTypes, result)
+			}
+		})
+	}
+}
+
+func TestShortFunctionName(t *testing.T) {
+	tests := []struct {
+		name         string
+		functionName string
+		expected     string
+	}{
+		{"Package function", "github.com/acme/svc/service.EventHandlerFunction", "EventHandlerFunction"},
+		{"Method value", "github.com/acme/svc/service.(*Handlers).OnOrder-fm", "OnOrder"},
+		{"Main package", "main.handleOrder", "handleOrder"},
+		{"Closure", "main.main.func1", ""},
+		{"Empty", "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := shortFunctionName(test.functionName); result != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, result)
+			}
+		})
+	}
+}
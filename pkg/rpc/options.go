@@ -0,0 +1,118 @@
+package rpc
+
+import (
+	"crypto/tls"
+	"fmt"
+	"github.com/joejoe-am/namego/configs"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultExchangeName is the exchange Nameko services use for RPC.
+	DefaultExchangeName = "nameko-rpc"
+	// DefaultClientServiceName names the reply queue of clients that are not
+	// part of a service, like Nameko's standalone RPC proxy.
+	DefaultClientServiceName = "standalone_rpc_proxy"
+
+	// The remaining defaults mirror Nameko's.
+	DefaultMaxWorkers         = 10
+	DefaultParentCallsTracked = 10
+	DefaultSerializer         = "json"
+	DefaultHeartbeat          = 60 * time.Second
+)
+
+// Options configures RPC clients and servers. The zero value is usable and
+// talks to Nameko services on the default exchange.
+type Options struct {
+	ServiceName        string         // Name of the calling service, used in the reply queue name
+	ExchangeName       string         // RPC exchange, DefaultExchangeName if empty
+	MaxWorkers         int            // Requests a server handles concurrently, DefaultMaxWorkers if zero
+	PrefetchCount      int            // Unacked requests a server receives, MaxWorkers if zero
+	ParentCallsTracked int            // Parent call IDs forwarded in the call_id_stack header, DefaultParentCallsTracked if zero
+	Serializer         string         // Only DefaultSerializer is supported
+	Heartbeat          *time.Duration // AMQP heartbeat used by Dial, DefaultHeartbeat if nil; zero leaves the interval to the broker
+	TLS                *tls.Config    // Makes Dial connect over TLS
+}
+
+// OptionsFromConfigs returns the Options described by configuration loaded
+// with the configs package, including its Nameko keys.
+func OptionsFromConfigs(cfg *configs.Configs) (Options, error) {
+	tlsConfig, err := cfg.AMQPSSL.TLSConfig()
+	if err != nil {
+		return Options{}, fmt.Errorf("AMQP_SSL: %w", err)
+	}
+
+	// HEARTBEAT: 0 is kept, Nameko uses it to turn the client heartbeat off.
+	var heartbeat *time.Duration
+	if cfg.Heartbeat != nil {
+		seconds := time.Duration(*cfg.Heartbeat) * time.Second
+		heartbeat = &seconds
+	}
+
+	return Options{
+		ServiceName:        cfg.ServiceName,
+		ExchangeName:       cfg.ExchangeName,
+		MaxWorkers:         cfg.MaxWorkers,
+		PrefetchCount:      cfg.PrefetchCount,
+		ParentCallsTracked: cfg.ParentCallsTracked,
+		Serializer:         cfg.Serializer,
+		Heartbeat:          heartbeat,
+		TLS:                tlsConfig,
+	}, nil
+}
+
+func (o Options) withDefaults() Options {
+	if o.ServiceName == "" {
+		o.ServiceName = DefaultClientServiceName
+	}
+	if o.ExchangeName == "" {
+		o.ExchangeName = DefaultExchangeName
+	}
+	if o.MaxWorkers <= 0 {
+		o.MaxWorkers = DefaultMaxWorkers
+	}
+	if o.PrefetchCount <= 0 {
+		o.PrefetchCount = o.MaxWorkers
+	}
+	if o.ParentCallsTracked <= 0 {
+		o.ParentCallsTracked = DefaultParentCallsTracked
+	}
+	if o.Serializer == "" {
+		o.Serializer = DefaultSerializer
+	}
+	if o.Heartbeat == nil {
+		heartbeat := DefaultHeartbeat
+		o.Heartbeat = &heartbeat
+	}
+	return o
+}
+
+func (o Options) validate() error {
+	if o.Serializer != DefaultSerializer {
+		return fmt.Errorf("rpc: unsupported serializer %q, only %q is supported", o.Serializer, DefaultSerializer)
+	}
+	return nil
+}
+
+// Dial connects to RabbitMQ at url with the heartbeat and TLS settings of
+// options. Like Nameko with AMQP_SSL, an amqp:// URL is dialled over TLS
+// when options.TLS is set.
+func Dial(url string, options ...Options) (*amqp.Connection, error) {
+	var opts Options
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	opts = opts.withDefaults()
+
+	if opts.TLS != nil && strings.HasPrefix(url, "amqp://") {
+		url = "amqps://" + strings.TrimPrefix(url, "amqp://")
+	}
+
+	return amqp.DialConfig(url, amqp.Config{
+		Heartbeat:       *opts.Heartbeat,
+		TLSClientConfig: opts.TLS,
+		Locale:          "en_US",
+	})
+}
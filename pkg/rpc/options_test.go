@@ -0,0 +1,79 @@
+package rpc
+
+import (
+	"github.com/joejoe-am/namego/configs"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithCallID(t *testing.T) {
+	clientOptions = Options{ServiceName: "gateway", ParentCallsTracked: 2}.withDefaults()
+	defer func() { clientOptions = Options{} }()
+
+	tests := []struct {
+		name          string
+		contextData   map[string]interface{}
+		expectedStack []interface{}
+	}{
+		{
+			name:          "No parent calls",
+			contextData:   nil,
+			expectedStack: []interface{}{"gateway.call.id"},
+		},
+		{
+			name:          "Parents truncated",
+			contextData:   map[string]interface{}{CallIDStackKey: []string{"a.x.1", "b.y.2", "c.z.3"}},
+			expectedStack: []interface{}{"b.y.2", "c.z.3", "gateway.call.id"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := withCallID(test.contextData, "id")
+			if stack := result[CallIDStackKey]; !reflect.DeepEqual(stack, test.expectedStack) {
+				t.Errorf("expected stack %v, got %v", test.expectedStack, stack)
+			}
+		})
+	}
+}
+
+func TestOptionsDefaults(t *testing.T) {
+	options := Options{MaxWorkers: 4}.withDefaults()
+
+	if options.PrefetchCount != 4 {
+		t.Errorf("expected prefetch count to default to MaxWorkers, got %d", options.PrefetchCount)
+	}
+	if err := options.validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := (Options{Serializer: "pickle"}).withDefaults().validate(); err == nil {
+		t.Errorf("expected error for unsupported serializer")
+	}
+}
+
+func TestOptionsFromConfigsHeartbeat(t *testing.T) {
+	zero, thirty := 0, 30
+
+	tests := []struct {
+		name      string
+		heartbeat *int
+		expected  time.Duration
+	}{
+		{"Unset", nil, DefaultHeartbeat},
+		{"Zero", &zero, 0},
+		{"Seconds", &thirty, 30 * time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options, err := OptionsFromConfigs(&configs.Configs{Heartbeat: test.heartbeat})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if heartbeat := *options.withDefaults().Heartbeat; heartbeat != test.expected {
+				t.Errorf("expected heartbeat %v, got %v", test.expected, heartbeat)
+			}
+		})
+	}
+}
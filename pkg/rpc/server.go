@@ -2,25 +2,42 @@ package rpc
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/joejoe-am/namego/pkg/metrics"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"log"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
+// errHandlerPanicked stands in for the error of a method that panicked.
+var errHandlerPanicked = errors.New("rpc: method panicked")
+
 type Server struct {
 	serviceName    string
 	amqpConnection *amqp.Connection
 	amqpChannel    *amqp.Channel
 	methods        map[string]func(args interface{}, kwargs map[string]interface{}) (interface{}, error)
+	consuming      atomic.Bool
+	options        Options
 }
 
-// NewServer initializes and returns a Server instance.
-func NewServer(serviceName string, amqpConnection *amqp.Connection) *Server {
+// NewServer initializes and returns a Server instance. The ServiceName of
+// options is ignored, serviceName is used instead.
+func NewServer(serviceName string, amqpConnection *amqp.Connection, options ...Options) *Server {
+	var opts Options
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	opts.ServiceName = serviceName
+
 	return &Server{
 		serviceName:    serviceName,
 		amqpConnection: amqpConnection,
 		methods:        make(map[string]func(args interface{}, kwargs map[string]interface{}) (interface{}, error)),
+		options:        opts.withDefaults(),
 	}
 }
 
@@ -31,7 +48,10 @@ func (s *Server) RegisterMethod(methodName string, handler func(args interface{}
 
 // Start begins listening for RPC requests on the service's queue.
 func (s *Server) Start() error {
-	var err error
+	err := s.options.validate()
+	if err != nil {
+		return err
+	}
 
 	queueName := fmt.Sprintf(RpcQueueTemplate, s.serviceName)
 	routingKey := fmt.Sprintf("%s.*", s.serviceName)
@@ -60,7 +80,7 @@ func (s *Server) Start() error {
 	err = s.amqpChannel.QueueBind(
 		queueName,
 		routingKey,
-		Cfg.ExchangeName,
+		s.options.ExchangeName,
 		false,
 		nil,
 	)
@@ -70,10 +90,14 @@ func (s *Server) Start() error {
 	}
 
 	err = s.amqpChannel.Qos(
-		2,
+		s.options.PrefetchCount,
 		0,
 		false,
 	)
+	if err != nil {
+		log.Printf("error setting qos: %v", err)
+		return err
+	}
 
 	msgs, err := s.amqpChannel.Consume(
 		queueName,
@@ -90,7 +114,11 @@ func (s *Server) Start() error {
 		return err
 	}
 
-	workerPool := make(chan struct{}, 2)
+	workerPool := make(chan struct{}, s.options.MaxWorkers)
+	metrics.RPCServerWorkers.WithLabelValues(s.serviceName).Set(float64(cap(workerPool)))
+
+	s.consuming.Store(true)
+	defer s.consuming.Store(false)
 
 	// TODO: handle worker pool better (worker pool pattern)
 
@@ -153,22 +181,41 @@ func (s *Server) processMessage(msg amqp.Delivery) error {
 		return s.sendResponse(msg, nil, fmt.Errorf("method not found: %s", methodName))
 	}
 
-	result, err := handler(request.Args, request.Kwargs)
+	result, err := s.callMethod(methodName, handler, request.Args, request.Kwargs)
 
 	return s.sendResponse(msg, result, err)
 }
 
+// callMethod runs handler and records the server metrics for the call. A
+// panicking handler is counted as an error before the panic propagates to
+// handleRequest.
+func (s *Server) callMethod(methodName string, handler func(args interface{}, kwargs map[string]interface{}) (interface{}, error), args interface{}, kwargs map[string]interface{}) (result interface{}, err error) {
+	inFlight := metrics.RPCServerInFlight.WithLabelValues(s.serviceName, methodName)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	start := time.Now()
+	completed := false
+	defer func() {
+		outcome := metrics.Result(err)
+		if !completed {
+			outcome = metrics.Result(errHandlerPanicked)
+		}
+		metrics.RPCServerRequests.WithLabelValues(s.serviceName, methodName, outcome).Inc()
+		metrics.RPCServerDuration.WithLabelValues(s.serviceName, methodName).Observe(time.Since(start).Seconds())
+	}()
+
+	result, err = handler(args, kwargs)
+	completed = true
+
+	return result, err
+}
+
 // sendResponse constructs and sends a success response.
 func (s *Server) sendResponse(msg amqp.Delivery, result interface{}, err error) error {
 	response := Response{}
 	if err != nil {
-		response.Error = &struct {
-			ExcType   string                 `json:"exc_type"`
-			ExcPath   string                 `json:"exc_path"`
-			ExcArgs   []interface{}          `json:"exc_args"`
-			ExcKwargs map[string]interface{} `json:"exc_kwargs"`
-			Value     string                 `json:"value"`
-		}{
+		response.Error = &RemoteError{
 			ExcType:   "",
 			ExcPath:   "",
 			ExcArgs:   []interface{}{err.Error()},
@@ -186,7 +233,7 @@ func (s *Server) sendResponse(msg amqp.Delivery, result interface{}, err error)
 	}
 
 	publishErr := s.amqpChannel.Publish(
-		Cfg.ExchangeName,
+		s.options.ExchangeName,
 		msg.ReplyTo,
 		false,
 		false,
@@ -203,3 +250,9 @@ func (s *Server) sendResponse(msg amqp.Delivery, result interface{}, err error)
 
 	return nil
 }
+
+// IsConsuming reports whether the server is consuming RPC requests. It turns
+// false when the delivery channel closes, e.g. because the connection died.
+func (s *Server) IsConsuming() bool {
+	return s.consuming.Load() && s.amqpChannel != nil && !s.amqpChannel.IsClosed()
+}
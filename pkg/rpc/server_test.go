@@ -0,0 +1,54 @@
+package rpc
+
+import (
+	"github.com/joejoe-am/namego/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"testing"
+)
+
+// nackRecorder records whether a delivery was acked or nacked.
+type nackRecorder struct {
+	acked, nacked bool
+}
+
+func (r *nackRecorder) Ack(tag uint64, multiple bool) error {
+	r.acked = true
+	return nil
+}
+
+func (r *nackRecorder) Nack(tag uint64, multiple bool, requeue bool) error {
+	r.nacked = true
+	return nil
+}
+
+func (r *nackRecorder) Reject(tag uint64, requeue bool) error {
+	return r.Nack(tag, false, requeue)
+}
+
+func TestHandleRequestPanickingMethod(t *testing.T) {
+	server := NewServer("panicky", nil)
+	server.RegisterMethod("explode", func(args interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		panic("boom")
+	})
+
+	recorder := &nackRecorder{}
+	server.handleRequest(amqp.Delivery{
+		Acknowledger: recorder,
+		RoutingKey:   "panicky.explode",
+		Body:         []byte(`{"args": [], "kwargs": {}}`),
+	})
+
+	if !recorder.nacked || recorder.acked {
+		t.Errorf("expected the delivery to be nacked only, got acked %v, nacked %v", recorder.acked, recorder.nacked)
+	}
+	if inFlight := testutil.ToFloat64(metrics.RPCServerInFlight.WithLabelValues("panicky", "explode")); inFlight != 0 {
+		t.Errorf("expected no calls in flight, got %v", inFlight)
+	}
+	if errors := testutil.ToFloat64(metrics.RPCServerRequests.WithLabelValues("panicky", "explode", "error")); errors != 1 {
+		t.Errorf("expected one failed call, got %v", errors)
+	}
+	if observed := testutil.CollectAndCount(metrics.RPCServerDuration); observed == 0 {
+		t.Errorf("expected the call duration to be observed")
+	}
+}
@@ -2,24 +2,66 @@ package web
 
 import (
 	"github.com/valyala/fasthttp"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Handler returns the server handler.
 type Handler func(ctx *fasthttp.RequestCtx)
 
 type Server struct {
-	server *fasthttp.Server
-	routes []Route
+	server     *fasthttp.Server
+	config     Config
+	routes     []*Route
+	tree       *node
+	middleware []Middleware
+	pipeline   Handler // Global middleware wrapped around dispatch
+
+	mu           sync.Mutex
+	certificates *certificateReloader
+	stopped      chan struct{} // Closed on Shutdown
+	stopOnce     sync.Once
 }
 
 // Config holds the configuration for the HTTP server
 type Config struct {
 	Addr string // Address to bind the server to
+
+	ReadTimeout        time.Duration // Maximum duration for reading a full request, including the body
+	WriteTimeout       time.Duration // Maximum duration for writing a response
+	IdleTimeout        time.Duration // Maximum time to wait for the next request on keep-alive connections
+	MaxRequestBodySize int           // Maximum request body size in bytes, fasthttp's 4MB default if zero
+	Concurrency        int           // Maximum number of concurrent connections, fasthttp's default if zero
+
+	CertFile          string        // PEM certificate, enables TLS together with KeyFile
+	KeyFile           string        // PEM private key
+	TLSReloadInterval time.Duration // How often to check the certificate files for changes, zero disables
+
+	// RedirectTrailingSlash redirects requests for /foo/ to /foo (or the other
+	// way around) when only the other form is registered.
+	RedirectTrailingSlash bool
+
+	NotFoundHandler         Handler      // Answers requests matching no route, a 404 Problem by default
+	MethodNotAllowedHandler Handler      // Answers requests for paths registered with other methods, the Allow header is already set
+	ErrorHandler            ErrorHandler // Writes errors passed to Error, DefaultErrorHandler if nil
 }
 
-func New() *Server {
+func New(config ...Config) *Server {
 	http := &Server{
-		routes: make([]Route, 0),
+		routes:  make([]*Route, 0),
+		tree:    newNode(),
+		stopped: make(chan struct{}),
+	}
+
+	if len(config) > 0 {
+		http.config = config[0]
+	}
+	if http.config.NotFoundHandler == nil {
+		http.config.NotFoundHandler = notFound
+	}
+	if http.config.MethodNotAllowedHandler == nil {
+		http.config.MethodNotAllowedHandler = methodNotAllowed
 	}
 
 	http.init()
@@ -28,7 +70,15 @@ func New() *Server {
 }
 
 func (s *Server) init() *Server {
-	s.server = &fasthttp.Server{}
+	s.server = &fasthttp.Server{
+		ReadTimeout:        s.config.ReadTimeout,
+		WriteTimeout:       s.config.WriteTimeout,
+		IdleTimeout:        s.config.IdleTimeout,
+		MaxRequestBodySize: s.config.MaxRequestBodySize,
+		Concurrency:        s.config.Concurrency,
+		CloseOnShutdown:    true,
+	}
+	s.pipeline = s.dispatch
 
 	// fasthttp server settings
 	s.server.Handler = s.Handler()
@@ -38,75 +88,174 @@ func (s *Server) init() *Server {
 
 func (s *Server) Handler() fasthttp.RequestHandler {
 	return func(ctx *fasthttp.RequestCtx) {
-		for _, route := range s.routes {
-			if matchRoute(ctx, route) {
-				// Execute middleware in sequence
-				for _, mw := range route.Middleware {
-					mw(ctx)
-					if ctx.Response.StatusCode() != fasthttp.StatusOK {
-						// Stop processing if a middleware has set an error status
-						return
-					}
-				}
-
-				// Call the main handler
-				route.Handler(ctx)
-				return
-			}
+		if s.config.ErrorHandler != nil {
+			ctx.SetUserValue(errorHandlerKey, s.config.ErrorHandler)
+		}
+		s.pipeline(ctx)
+	}
+}
+
+// Use registers global middleware. It wraps every request, including those
+// that do not match any route, and runs before route and group middleware.
+func (s *Server) Use(middleware ...Middleware) Router {
+	s.middleware = append(s.middleware, middleware...)
+	s.pipeline = chain(s.middleware, s.dispatch)
+	return s
+}
+
+// dispatch routes the request to the matching route's pipeline. Paths that
+// exist for other methods are answered with 405, or 204 for OPTIONS requests,
+// listing the registered methods in the Allow header.
+func (s *Server) dispatch(ctx *fasthttp.RequestCtx) {
+	path := string(ctx.Path())
+
+	route, n := s.match(ctx, path)
+	if route != nil {
+		route.pipeline(ctx)
+		return
+	}
+
+	if n != nil {
+		ctx.Response.Header.Set("Allow", n.allow())
+		if ctx.IsOptions() {
+			ctx.SetStatusCode(fasthttp.StatusNoContent)
+		} else {
+			s.config.MethodNotAllowedHandler(ctx)
 		}
-		// Default 404 handler
-		ctx.Error("Not Found", fasthttp.StatusNotFound)
+		return
 	}
+
+	if s.config.RedirectTrailingSlash && s.redirectTrailingSlash(ctx, path) {
+		return
+	}
+
+	s.config.NotFoundHandler(ctx)
+}
+
+func notFound(ctx *fasthttp.RequestCtx) {
+	Error(ctx, NewHTTPError(fasthttp.StatusNotFound, fasthttp.StatusMessage(fasthttp.StatusNotFound)))
+}
+
+func methodNotAllowed(ctx *fasthttp.RequestCtx) {
+	Error(ctx, NewHTTPError(fasthttp.StatusMethodNotAllowed, fasthttp.StatusMessage(fasthttp.StatusMethodNotAllowed)))
+}
+
+// match looks up the route for the request method and path and stores the
+// captured path parameters as user values on ctx. HEAD requests fall back to
+// the GET route, fasthttp omits the body of HEAD responses. When the path
+// matches but the method does not, the matched node is returned without a route.
+func (s *Server) match(ctx *fasthttp.RequestCtx, path string) (*Route, *node) {
+	n, params := s.tree.lookup(path)
+	if n == nil {
+		return nil, nil
+	}
+
+	route, ok := n.routes[string(ctx.Method())]
+	if !ok && ctx.IsHead() {
+		route, ok = n.routes[MethodGet]
+	}
+	if !ok {
+		return nil, n
+	}
+
+	for _, p := range params {
+		ctx.SetUserValue(p.key, p.value)
+	}
+	ctx.SetUserValue(routePatternKey, route.Path)
+
+	return route, n
+}
+
+// redirectTrailingSlash redirects to the path with the trailing slash added or
+// removed if that path is registered for the request method.
+func (s *Server) redirectTrailingSlash(ctx *fasthttp.RequestCtx, path string) bool {
+	var alternative string
+	if len(path) > 1 && strings.HasSuffix(path, "/") {
+		alternative = strings.TrimSuffix(path, "/")
+	} else {
+		alternative = path + "/"
+	}
+
+	n, _ := s.tree.lookup(alternative)
+	if n == nil || !n.allows(string(ctx.Method())) {
+		return false
+	}
+
+	// 301 may turn other methods into GET, 308 preserves method and body.
+	code := fasthttp.StatusMovedPermanently
+	if !ctx.IsGet() {
+		code = fasthttp.StatusPermanentRedirect
+	}
+
+	uri := ctx.URI()
+	if query := uri.QueryString(); len(query) > 0 {
+		alternative += "?" + string(query)
+	}
+	ctx.Redirect(alternative, code)
+
+	return true
 }
 
 // Add allows you to specify multiple HTTP methods to register a route.
-func (s *Server) Add(methods []string, path string, handler Handler, middleware ...Handler) Router {
-	s.routes = append(s.routes, Route{
-		Methods:    methods,
+// Paths may contain named parameters (/users/:id) and a trailing wildcard
+// (/files/*path), readable from handlers with Param.
+func (s *Server) Add(methods []string, path string, handler Handler, middleware ...Middleware) Router {
+	normalized := make([]string, len(methods))
+	for i, method := range methods {
+		normalized[i] = strings.ToUpper(method)
+	}
+
+	route := &Route{
+		Methods:    normalized,
 		Path:       path,
 		Handler:    handler,
 		Middleware: middleware,
-	})
+		pipeline:   chain(middleware, handler),
+	}
+
+	s.tree.insert(path, route)
+	s.routes = append(s.routes, route)
+
 	return s
 }
 
 // Get registers a route for GET methods that requests a representation
 // of the specified resource. Requests using GET should only retrieve data.
-func (s *Server) Get(path string, handler Handler, middleware ...Handler) Router {
+func (s *Server) Get(path string, handler Handler, middleware ...Middleware) Router {
 	return s.Add([]string{MethodGet}, path, handler, middleware...)
 }
 
 // Head registers a route for HEAD methods that asks for a response identical
 // to that of a GET request, but without the response body.
-func (s *Server) Head(path string, handler Handler, middleware ...Handler) Router {
+func (s *Server) Head(path string, handler Handler, middleware ...Middleware) Router {
 	return s.Add([]string{MethodHead}, path, handler, middleware...)
 }
 
 // Post registers a route for POST methods that is used to submit an entity to the
 // specified resource, often causing a change in state or side effects on the server.
-func (s *Server) Post(path string, handler Handler, middleware ...Handler) Router {
+func (s *Server) Post(path string, handler Handler, middleware ...Middleware) Router {
 	return s.Add([]string{MethodPost}, path, handler, middleware...)
 }
 
 // Put registers a route for PUT methods that replaces all current representations
 // of the target resource with the request payload.
-func (s *Server) Put(path string, handler Handler, middleware ...Handler) Router {
+func (s *Server) Put(path string, handler Handler, middleware ...Middleware) Router {
 	return s.Add([]string{MethodPut}, path, handler, middleware...)
 }
 
 // Patch registers a route for PATCH methods that is used to apply partial
 // modifications to a resource.
-func (s *Server) Patch(path string, handler Handler, middleware ...Handler) Router {
+func (s *Server) Patch(path string, handler Handler, middleware ...Middleware) Router {
 	return s.Add([]string{MethodPatch}, path, handler, middleware...)
 }
 
 // Options registers a route for OPTIONS methods that is used to describe the
 // communication options for the target resource.
-func (s *Server) Options(path string, handler Handler, middleware ...Handler) Router {
+func (s *Server) Options(path string, handler Handler, middleware ...Middleware) Router {
 	return s.Add([]string{MethodOptions}, path, handler, middleware...)
 }
 
 // Delete registers a route for DELETE methods that deletes the specified resource.
-func (s *Server) Delete(path string, handler Handler, middleware ...Handler) Router {
+func (s *Server) Delete(path string, handler Handler, middleware ...Middleware) Router {
 	return s.Add([]string{MethodDelete}, path, handler, middleware...)
 }
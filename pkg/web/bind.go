@@ -0,0 +1,179 @@
+package web
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"reflect"
+	"strconv"
+)
+
+// BindError describes a request value that could not be bound.
+type BindError struct {
+	Source string // "body", "query" or "path"
+	Field  string
+	Err    error
+}
+
+func (e *BindError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("invalid request %s: %v", e.Source, e.Err)
+	}
+	return fmt.Sprintf("invalid %s parameter %q: %v", e.Source, e.Field, e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// Bind decodes the request into v, which must be a pointer to a struct. A
+// JSON body is decoded first, then fields tagged `query:"name"` and
+// `path:"name"` are set from the query string and path parameters.
+func Bind(ctx *fasthttp.RequestCtx, v interface{}) error {
+	if err := BindJSON(ctx, v); err != nil {
+		return err
+	}
+	if err := BindQuery(ctx, v); err != nil {
+		return err
+	}
+	return BindPath(ctx, v)
+}
+
+// BindJSON decodes a JSON request body into v. Empty bodies are ignored.
+func BindJSON(ctx *fasthttp.RequestCtx, v interface{}) error {
+	body := ctx.PostBody()
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	contentType := ctx.Request.Header.ContentType()
+	if len(contentType) > 0 && !bytes.HasPrefix(contentType, []byte("application/json")) {
+		return &BindError{Source: "body", Err: fmt.Errorf("unsupported content type %q", contentType)}
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return &BindError{Source: "body", Err: err}
+	}
+	return nil
+}
+
+// BindQuery sets fields tagged `query:"name"` from the query string.
+func BindQuery(ctx *fasthttp.RequestCtx, v interface{}) error {
+	args := ctx.QueryArgs()
+	return bindTagged(v, "query", func(name string) []string {
+		var values []string
+		for _, value := range args.PeekMulti(name) {
+			values = append(values, string(value))
+		}
+		return values
+	})
+}
+
+// BindPath sets fields tagged `path:"name"` from the route's path parameters.
+func BindPath(ctx *fasthttp.RequestCtx, v interface{}) error {
+	return bindTagged(v, "path", func(name string) []string {
+		if value, ok := ctx.UserValue(name).(string); ok {
+			return []string{value}
+		}
+		return nil
+	})
+}
+
+// bindTagged sets every field of the struct v points to that has the given
+// tag, using lookup to find the values for the tag's name.
+func bindTagged(v interface{}, tag string, lookup func(name string) []string) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.Elem().Kind() != reflect.Struct {
+		// Only structs have tagged fields, other types are bound from the body only.
+		return nil
+	}
+	target = target.Elem()
+
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Type().Field(i)
+		name := field.Tag.Get(tag)
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+
+		values := lookup(name)
+		if len(values) == 0 {
+			continue
+		}
+
+		if err := setField(target.Field(i), values); err != nil {
+			return &BindError{Source: tag, Field: name, Err: err}
+		}
+	}
+
+	return nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// setField parses values into field. Slices receive every value, other
+// kinds the first one.
+func setField(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice && !field.Type().Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	return setValue(field, values[0])
+}
+
+func setValue(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(parsed)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return nil
+}
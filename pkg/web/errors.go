@@ -0,0 +1,84 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/valyala/fasthttp"
+	"log"
+)
+
+const ContentTypeProblemJSON = "application/problem+json"
+
+const errorHandlerKey = "web.error_handler"
+
+// Problem is an RFC 7807 problem details document, the default format of
+// error responses.
+type Problem struct {
+	Type     string `json:"type,omitempty"` // Omitted, i.e. "about:blank", by default
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// ErrorHandler turns an error into a response. Set Config.ErrorHandler to
+// change how every error of a server is written.
+type ErrorHandler func(ctx *fasthttp.RequestCtx, err error)
+
+// HandlerFunc is a handler that returns its error instead of writing it.
+type HandlerFunc func(ctx *fasthttp.RequestCtx) error
+
+// Handle adapts fn into a Handler, passing returned errors to Error.
+func Handle(fn HandlerFunc) Handler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if err := fn(ctx); err != nil {
+			Error(ctx, err)
+		}
+	}
+}
+
+// Error responds with err using the ErrorHandler of the server handling ctx,
+// or DefaultErrorHandler if it has none.
+func Error(ctx *fasthttp.RequestCtx, err error) {
+	if handler, ok := ctx.UserValue(errorHandlerKey).(ErrorHandler); ok {
+		handler(ctx, err)
+		return
+	}
+	DefaultErrorHandler(ctx, err)
+}
+
+// DefaultErrorHandler writes err as a Problem. The status code and detail are
+// taken from err if it is an HTTPError, other errors are answered with 500
+// without exposing their message. Server errors are logged unless they are
+// HTTPErrors without an underlying error.
+func DefaultErrorHandler(ctx *fasthttp.RequestCtx, err error) {
+	status := fasthttp.StatusInternalServerError
+	var detail string
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		status = httpErr.Code
+		detail = httpErr.Message
+		if status >= fasthttp.StatusInternalServerError && httpErr.Err != nil {
+			log.Printf("request error: %v", err)
+		}
+	} else {
+		log.Printf("request error: %v", err)
+	}
+
+	problem := Problem{
+		Title:    fasthttp.StatusMessage(status),
+		Status:   status,
+		Instance: string(ctx.Path()),
+	}
+	if detail != problem.Title {
+		problem.Detail = detail
+	}
+
+	// Marshalling strings and an int cannot fail.
+	body, _ := json.Marshal(problem)
+
+	ctx.SetStatusCode(status)
+	ctx.SetContentType(ContentTypeProblemJSON)
+	ctx.SetBody(body)
+}
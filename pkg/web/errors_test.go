@@ -0,0 +1,170 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"github.com/valyala/fasthttp"
+	"log"
+	"os"
+	"testing"
+)
+
+func TestErrorHandlers(t *testing.T) {
+	handlers := func(server *Server) *Server {
+		server.Use(func(next Handler) Handler {
+			return func(ctx *fasthttp.RequestCtx) {
+				ctx.Response.Header.Set("X-Service", "items")
+				next(ctx)
+			}
+		})
+		server.Get("/items/:id", Handle(func(ctx *fasthttp.RequestCtx) error {
+			switch Param(ctx, "id") {
+			case "missing":
+				return NewHTTPError(fasthttp.StatusNotFound, "item missing does not exist")
+			case "broken":
+				return errors.New("database password is hunter2")
+			}
+			ctx.WriteString("item")
+			return nil
+		}))
+		return server
+	}
+
+	defaults := handlers(New())
+	custom := handlers(New(Config{
+		NotFoundHandler: func(ctx *fasthttp.RequestCtx) {
+			ctx.Error("nothing here", fasthttp.StatusNotFound)
+		},
+		MethodNotAllowedHandler: func(ctx *fasthttp.RequestCtx) {
+			ctx.SetStatusCode(fasthttp.StatusTeapot)
+		},
+		ErrorHandler: func(ctx *fasthttp.RequestCtx, err error) {
+			ctx.SetStatusCode(fasthttp.StatusBadGateway)
+			ctx.SetBodyString(err.Error())
+		},
+	}))
+
+	tests := []struct {
+		name         string
+		server       *Server
+		method       string
+		path         string
+		expectedCode int
+		expectedType string
+		expectedBody string
+	}{
+		{
+			name:         "Default not found",
+			server:       defaults,
+			method:       MethodGet,
+			path:         "/nope",
+			expectedCode: fasthttp.StatusNotFound,
+			expectedType: ContentTypeProblemJSON,
+			expectedBody: `{"title":"Not Found","status":404,"instance":"/nope"}`,
+		},
+		{
+			name:         "Default method not allowed",
+			server:       defaults,
+			method:       MethodPost,
+			path:         "/items/1",
+			expectedCode: fasthttp.StatusMethodNotAllowed,
+			expectedType: ContentTypeProblemJSON,
+			expectedBody: `{"title":"Method Not Allowed","status":405,"instance":"/items/1"}`,
+		},
+		{
+			name:         "Returned HTTP error",
+			server:       defaults,
+			method:       MethodGet,
+			path:         "/items/missing",
+			expectedCode: fasthttp.StatusNotFound,
+			expectedType: ContentTypeProblemJSON,
+			expectedBody: `{"title":"Not Found","status":404,"detail":"item missing does not exist","instance":"/items/missing"}`,
+		},
+		{
+			name:         "Returned error is masked",
+			server:       defaults,
+			method:       MethodGet,
+			path:         "/items/broken",
+			expectedCode: fasthttp.StatusInternalServerError,
+			expectedType: ContentTypeProblemJSON,
+			expectedBody: `{"title":"Internal Server Error","status":500,"instance":"/items/broken"}`,
+		},
+		{
+			name:         "Custom not found",
+			server:       custom,
+			method:       MethodGet,
+			path:         "/nope",
+			expectedCode: fasthttp.StatusNotFound,
+			expectedBody: "nothing here",
+		},
+		{
+			name:         "Custom method not allowed",
+			server:       custom,
+			method:       MethodPost,
+			path:         "/items/1",
+			expectedCode: fasthttp.StatusTeapot,
+		},
+		{
+			name:         "Custom error handler",
+			server:       custom,
+			method:       MethodGet,
+			path:         "/items/broken",
+			expectedCode: fasthttp.StatusBadGateway,
+			expectedBody: "database password is hunter2",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := &fasthttp.RequestCtx{}
+			ctx.Request.SetRequestURI(test.path)
+			ctx.Request.Header.SetMethod(test.method)
+
+			test.server.Handler()(ctx)
+
+			if code := ctx.Response.StatusCode(); code != test.expectedCode {
+				t.Errorf("expected status %d, got %d: %s", test.expectedCode, code, ctx.Response.Body())
+			}
+			if test.expectedType != "" {
+				if contentType := string(ctx.Response.Header.ContentType()); contentType != test.expectedType {
+					t.Errorf("expected content type %s, got %s", test.expectedType, contentType)
+				}
+				if service := string(ctx.Response.Header.Peek("X-Service")); service != "items" {
+					t.Errorf("expected middleware headers to be kept, got X-Service %q", service)
+				}
+			}
+			if test.expectedBody != "" && string(ctx.Response.Body()) != test.expectedBody {
+				t.Errorf("expected body %s, got %s", test.expectedBody, ctx.Response.Body())
+			}
+		})
+	}
+}
+
+func TestDefaultErrorHandlerLogging(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedLogged bool
+	}{
+		{"Plain error", errors.New("connection refused"), true},
+		{"Wrapped server error", &HTTPError{Code: fasthttp.StatusInternalServerError, Message: "Internal Server Error", Err: errors.New("connection refused")}, true},
+		{"Server error without cause", NewHTTPError(fasthttp.StatusServiceUnavailable, "try again later"), false},
+		{"Client error with cause", &HTTPError{Code: fasthttp.StatusBadRequest, Message: "invalid JSON", Err: errors.New("unexpected EOF")}, false},
+	}
+
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output.Reset()
+
+			DefaultErrorHandler(&fasthttp.RequestCtx{}, test.err)
+
+			if logged := output.Len() > 0; logged != test.expectedLogged {
+				t.Errorf("expected logged %v, got %q", test.expectedLogged, output.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package web
+
+import "strings"
+
+// Group is a Router that registers routes on its server under a shared path
+// prefix, running the group's middleware before each route's own middleware.
+type Group struct {
+	server     *Server
+	prefix     string
+	middleware []Middleware
+}
+
+// Group creates a sub-router mounted at prefix. Groups can be nested, the
+// prefixes and middleware of the parent groups are applied first.
+func (s *Server) Group(prefix string, middleware ...Middleware) Router {
+	return &Group{
+		server:     s,
+		prefix:     joinPaths("", prefix),
+		middleware: middleware,
+	}
+}
+
+// Route creates a group at prefix and passes it to fn, which allows packages
+// to expose their routes as a func(web.Router) and be mounted anywhere.
+func (s *Server) Route(prefix string, fn func(router Router), middleware ...Middleware) Router {
+	group := s.Group(prefix, middleware...)
+	fn(group)
+	return group
+}
+
+// Use adds middleware to the group, it applies to routes registered afterwards.
+func (g *Group) Use(middleware ...Middleware) Router {
+	g.middleware = combineMiddleware(g.middleware, middleware)
+	return g
+}
+
+// Group creates a nested group below this group's prefix.
+func (g *Group) Group(prefix string, middleware ...Middleware) Router {
+	return &Group{
+		server:     g.server,
+		prefix:     joinPaths(g.prefix, prefix),
+		middleware: combineMiddleware(g.middleware, middleware),
+	}
+}
+
+// Route creates a nested group at prefix and passes it to fn.
+func (g *Group) Route(prefix string, fn func(router Router), middleware ...Middleware) Router {
+	group := g.Group(prefix, middleware...)
+	fn(group)
+	return group
+}
+
+// Add registers a route for the given methods below the group's prefix.
+func (g *Group) Add(methods []string, path string, handler Handler, middleware ...Middleware) Router {
+	g.server.Add(methods, joinPaths(g.prefix, path), handler, combineMiddleware(g.middleware, middleware)...)
+	return g
+}
+
+// Describe attaches documentation to the most recently registered route.
+func (g *Group) Describe(doc RouteDoc) Router {
+	g.server.Describe(doc)
+	return g
+}
+
+// Get registers a GET route below the group's prefix.
+func (g *Group) Get(path string, handler Handler, middleware ...Middleware) Router {
+	return g.Add([]string{MethodGet}, path, handler, middleware...)
+}
+
+// Head registers a HEAD route below the group's prefix.
+func (g *Group) Head(path string, handler Handler, middleware ...Middleware) Router {
+	return g.Add([]string{MethodHead}, path, handler, middleware...)
+}
+
+// Post registers a POST route below the group's prefix.
+func (g *Group) Post(path string, handler Handler, middleware ...Middleware) Router {
+	return g.Add([]string{MethodPost}, path, handler, middleware...)
+}
+
+// Put registers a PUT route below the group's prefix.
+func (g *Group) Put(path string, handler Handler, middleware ...Middleware) Router {
+	return g.Add([]string{MethodPut}, path, handler, middleware...)
+}
+
+// Patch registers a PATCH route below the group's prefix.
+func (g *Group) Patch(path string, handler Handler, middleware ...Middleware) Router {
+	return g.Add([]string{MethodPatch}, path, handler, middleware...)
+}
+
+// Options registers an OPTIONS route below the group's prefix.
+func (g *Group) Options(path string, handler Handler, middleware ...Middleware) Router {
+	return g.Add([]string{MethodOptions}, path, handler, middleware...)
+}
+
+// Delete registers a DELETE route below the group's prefix.
+func (g *Group) Delete(path string, handler Handler, middleware ...Middleware) Router {
+	return g.Add([]string{MethodDelete}, path, handler, middleware...)
+}
+
+// joinPaths appends path to prefix, so that ("/api", "/users") becomes
+// "/api/users" and ("/api", "/") becomes "/api".
+func joinPaths(prefix, path string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if path == "" || path == "/" {
+		if prefix == "" {
+			return "/"
+		}
+		return prefix
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return prefix + path
+}
+
+// combineMiddleware returns a new slice holding first followed by second, so
+// that groups never share a backing array with their parents.
+func combineMiddleware(first, second []Middleware) []Middleware {
+	combined := make([]Middleware, 0, len(first)+len(second))
+	combined = append(combined, first...)
+	return append(combined, second...)
+}
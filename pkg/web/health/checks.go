@@ -0,0 +1,49 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"github.com/joejoe-am/namego/pkg/rpc"
+	"github.com/joejoe-am/namego/pkg/rpc/events"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// AMQPConnection checks that the AMQP connection is open.
+func AMQPConnection(conn *amqp.Connection) Check {
+	return func(ctx context.Context) error {
+		if conn == nil || conn.IsClosed() {
+			return errors.New("AMQP connection is closed")
+		}
+		return nil
+	}
+}
+
+// RPCServer checks that the RPC server is consuming requests.
+func RPCServer(server *rpc.Server) Check {
+	return func(ctx context.Context) error {
+		if !server.IsConsuming() {
+			return errors.New("RPC server is not consuming")
+		}
+		return nil
+	}
+}
+
+// RPCClient checks that the RPC reply queue is bound and consumed.
+func RPCClient() Check {
+	return func(ctx context.Context) error {
+		if !rpc.ClientReady() {
+			return errors.New("RPC reply queue is not being consumed")
+		}
+		return nil
+	}
+}
+
+// EventHandler checks that the event handler is consuming events.
+func EventHandler(handler *events.EventHandler) Check {
+	return func(ctx context.Context) error {
+		if !handler.IsConsuming() {
+			return errors.New("event handler is not consuming")
+		}
+		return nil
+	}
+}
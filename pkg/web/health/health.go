@@ -0,0 +1,158 @@
+// Package health serves liveness and readiness endpoints that aggregate
+// pluggable checks, such as the state of the AMQP connection and consumers.
+package health
+
+import (
+	"context"
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+
+	DefaultTimeout = 5 * time.Second
+)
+
+// Check returns an error if the component it checks is unhealthy.
+type Check func(ctx context.Context) error
+
+// Report is the JSON body of the health endpoints.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// CheckResult is the outcome of one check.
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Checker holds the liveness and readiness checks.
+type Checker struct {
+	timeout time.Duration
+
+	mu        sync.RWMutex
+	liveness  map[string]Check
+	readiness map[string]Check
+}
+
+// New returns a Checker whose checks time out after timeout, or DefaultTimeout.
+func New(timeout ...time.Duration) *Checker {
+	c := &Checker{
+		timeout:   DefaultTimeout,
+		liveness:  make(map[string]Check),
+		readiness: make(map[string]Check),
+	}
+	if len(timeout) > 0 && timeout[0] > 0 {
+		c.timeout = timeout[0]
+	}
+	return c
+}
+
+// AddLivenessCheck adds a check to /healthz. A failing liveness check makes
+// Kubernetes restart the pod, so only add checks a restart can fix.
+func (c *Checker) AddLivenessCheck(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.liveness[name] = check
+}
+
+// AddReadinessCheck adds a check to /readyz. A failing readiness check takes
+// the pod out of the load balancer.
+func (c *Checker) AddReadinessCheck(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readiness[name] = check
+}
+
+// Register adds the /healthz and /readyz routes to router.
+func (c *Checker) Register(router web.Router) {
+	router.Get("/healthz", c.LivenessHandler())
+	router.Get("/readyz", c.ReadinessHandler())
+}
+
+// LivenessHandler serves the liveness checks.
+func (c *Checker) LivenessHandler() web.Handler {
+	return c.handler(func() map[string]Check { return c.liveness })
+}
+
+// ReadinessHandler serves the readiness checks.
+func (c *Checker) ReadinessHandler() web.Handler {
+	return c.handler(func() map[string]Check { return c.readiness })
+}
+
+func (c *Checker) handler(checks func() map[string]Check) web.Handler {
+	return func(ctx *fasthttp.RequestCtx) {
+		c.mu.RLock()
+		// RequestCtx.Done tracks server shutdown rather than the request, so
+		// checks get their own context.
+		report := c.run(context.Background(), checks())
+		c.mu.RUnlock()
+
+		status := fasthttp.StatusOK
+		if report.Status != StatusOK {
+			status = fasthttp.StatusServiceUnavailable
+		}
+
+		ctx.Response.Header.Set("Cache-Control", "no-store")
+		if err := web.JSON(ctx, status, report); err != nil {
+			web.JSONError(ctx, fasthttp.StatusInternalServerError, err)
+		}
+	}
+}
+
+// run executes the checks concurrently, each bounded by the checker's timeout.
+func (c *Checker) run(parent context.Context, checks map[string]Check) Report {
+	ctx, cancel := context.WithTimeout(parent, c.timeout)
+	defer cancel()
+
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]CheckResult, len(names))
+	var wg sync.WaitGroup
+
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+
+			done := make(chan error, 1)
+			go func() { done <- check(ctx) }()
+
+			var err error
+			select {
+			case err = <-done:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+
+			if err != nil {
+				results[i] = CheckResult{Status: StatusFail, Error: err.Error()}
+			} else {
+				results[i] = CheckResult{Status: StatusOK}
+			}
+		}(i, checks[name])
+	}
+
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,56 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+	"testing"
+	"time"
+)
+
+func TestChecker(t *testing.T) {
+	checker := New(50 * time.Millisecond)
+	checker.AddLivenessCheck("process", func(ctx context.Context) error { return nil })
+	checker.AddReadinessCheck("amqp", func(ctx context.Context) error { return nil })
+	checker.AddReadinessCheck("events", func(ctx context.Context) error { return errors.New("not consuming") })
+	checker.AddReadinessCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	server := web.New()
+	checker.Register(server)
+
+	tests := []struct {
+		path           string
+		expectedCode   int
+		expectedChecks map[string]string
+	}{
+		{"/healthz", fasthttp.StatusOK, map[string]string{"process": StatusOK}},
+		{"/readyz", fasthttp.StatusServiceUnavailable, map[string]string{"amqp": StatusOK, "events": StatusFail, "slow": StatusFail}},
+	}
+
+	for _, test := range tests {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI(test.path)
+		ctx.Request.Header.SetMethod(web.MethodGet)
+
+		server.Handler()(ctx)
+
+		if code := ctx.Response.StatusCode(); code != test.expectedCode {
+			t.Errorf("%s: expected status %d, got %d", test.path, test.expectedCode, code)
+		}
+
+		var report Report
+		if err := json.Unmarshal(ctx.Response.Body(), &report); err != nil {
+			t.Fatalf("%s: invalid report: %v", test.path, err)
+		}
+		for name, status := range test.expectedChecks {
+			if report.Checks[name].Status != status {
+				t.Errorf("%s: expected %s to be %s, got %+v", test.path, name, status, report.Checks[name])
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/valyala/fasthttp"
+)
+
+const ContentTypeJSON = "application/json; charset=utf-8"
+
+// HTTPError is an error carrying the HTTP status code it should be answered with.
+type HTTPError struct {
+	Code    int
+	Message string
+	Err     error // Underlying error, not exposed to clients
+}
+
+// NewHTTPError returns an HTTPError with the given status code and message.
+func NewHTTPError(code int, message string) *HTTPError {
+	return &HTTPError{Code: code, Message: message}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// JSON writes v as a JSON response with the given status code.
+func JSON(ctx *fasthttp.RequestCtx, status int, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	ctx.SetStatusCode(status)
+	ctx.SetContentType(ContentTypeJSON)
+	ctx.SetBody(body)
+
+	return nil
+}
+
+// JSONError responds with err through Error, a Problem document by default.
+// The status code is taken from err if it is an HTTPError, otherwise status
+// is used and, for server errors, the message is replaced by the status text
+// so internals are not leaked.
+func JSONError(ctx *fasthttp.RequestCtx, status int, err error) {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		message := err.Error()
+		if status >= fasthttp.StatusInternalServerError {
+			message = fasthttp.StatusMessage(status)
+		}
+		err = &HTTPError{Code: status, Message: message, Err: err}
+	}
+
+	Error(ctx, err)
+}
+
+// TypedHandlerFunc is a handler that receives its request bound from the
+// HTTP request and returns a response to be written as JSON.
+type TypedHandlerFunc[Req, Resp any] func(ctx *fasthttp.RequestCtx, req Req) (Resp, error)
+
+// Typed adapts fn into a Handler. The request is bound with Bind, binding
+// failures are answered with 400 and errors returned by fn with 500 unless
+// they are HTTPErrors. The response is written as JSON with status 200, or
+// with the status fn set on ctx if it set one.
+func Typed[Req, Resp any](fn TypedHandlerFunc[Req, Resp]) Handler {
+	return func(ctx *fasthttp.RequestCtx) {
+		var req Req
+		if err := Bind(ctx, &req); err != nil {
+			JSONError(ctx, fasthttp.StatusBadRequest, err)
+			return
+		}
+
+		resp, err := fn(ctx, req)
+		if err != nil {
+			JSONError(ctx, fasthttp.StatusInternalServerError, err)
+			return
+		}
+
+		if err := JSON(ctx, ctx.Response.StatusCode(), resp); err != nil {
+			JSONError(ctx, fasthttp.StatusInternalServerError, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/valyala/fasthttp"
+	"testing"
+)
+
+type createItemRequest struct {
+	ID     int      `path:"id"`
+	Tags   []string `query:"tag"`
+	Notify *bool    `query:"notify"`
+	Name   string   `json:"name"`
+}
+
+type createItemResponse struct {
+	ID   int      `json:"id"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+func TestTyped(t *testing.T) {
+	server := New()
+	server.Post("/items/:id", Typed(func(ctx *fasthttp.RequestCtx, req createItemRequest) (createItemResponse, error) {
+		if req.Name == "" {
+			return createItemResponse{}, NewHTTPError(fasthttp.StatusUnprocessableEntity, `name "" is empty`)
+		}
+		if req.Notify == nil || !*req.Notify {
+			t.Errorf("expected notify to be bound")
+		}
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+		return createItemResponse{ID: req.ID, Name: req.Name, Tags: req.Tags}, nil
+	}))
+
+	tests := []struct {
+		name         string
+		uri          string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "Bound from path, query and body",
+			uri:          "/items/7?tag=a&tag=b&notify=true",
+			body:         `{"name": "box"}`,
+			expectedCode: fasthttp.StatusCreated,
+			expectedBody: `{"id":7,"name":"box","tags":["a","b"]}`,
+		},
+		{
+			name:         "Invalid path parameter",
+			uri:          "/items/seven",
+			expectedCode: fasthttp.StatusBadRequest,
+		},
+		{
+			name:         "Invalid body",
+			uri:          "/items/7",
+			body:         `{"name": `,
+			expectedCode: fasthttp.StatusBadRequest,
+		},
+		{
+			name:         "HTTP error with quotes",
+			uri:          "/items/7?notify=true",
+			body:         `{"name": ""}`,
+			expectedCode: fasthttp.StatusUnprocessableEntity,
+			expectedBody: `{"title":"Unprocessable Entity","status":422,"detail":"name \"\" is empty","instance":"/items/7"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := &fasthttp.RequestCtx{}
+			ctx.Request.SetRequestURI(test.uri)
+			ctx.Request.Header.SetMethod(MethodPost)
+			ctx.Request.Header.SetContentType("application/json")
+			ctx.Request.SetBodyString(test.body)
+
+			server.Handler()(ctx)
+
+			if code := ctx.Response.StatusCode(); code != test.expectedCode {
+				t.Errorf("expected status %d, got %d: %s", test.expectedCode, code, ctx.Response.Body())
+			}
+			if !json.Valid(ctx.Response.Body()) {
+				t.Errorf("expected valid JSON, got %s", ctx.Response.Body())
+			}
+			if test.expectedBody != "" && string(ctx.Response.Body()) != test.expectedBody {
+				t.Errorf("expected body %s, got %s", test.expectedBody, ctx.Response.Body())
+			}
+		})
+	}
+}
@@ -1,6 +1,75 @@
 package web
 
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+)
+
+// Listen serves HTTP on addr, or on Config.Addr if addr is empty. If
+// Config.CertFile and Config.KeyFile are set it serves HTTPS instead.
 func (s *Server) Listen(addr string) error {
-	// Start the server and listen on the specified address
-	return s.server.ListenAndServe(addr)
+	if addr == "" {
+		addr = s.config.Addr
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	if s.config.CertFile != "" || s.config.KeyFile != "" {
+		if s.config.CertFile == "" || s.config.KeyFile == "" {
+			_ = ln.Close()
+			return errors.New("web: both CertFile and KeyFile are required for TLS")
+		}
+
+		reloader, err := newCertificateReloader(s.config.CertFile, s.config.KeyFile)
+		if err != nil {
+			_ = ln.Close()
+			return err
+		}
+
+		s.mu.Lock()
+		s.certificates = reloader
+		s.mu.Unlock()
+
+		if s.config.TLSReloadInterval > 0 {
+			go reloader.watch(s.config.TLSReloadInterval, s.stopped)
+		}
+
+		ln = tls.NewListener(ln, &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.getCertificate,
+		})
+	}
+
+	return s.Serve(ln)
+}
+
+// Serve serves HTTP on ln until Shutdown is called. Use it for listeners
+// Listen does not create, such as in-memory listeners in tests.
+func (s *Server) Serve(ln net.Listener) error {
+	return s.server.Serve(ln)
+}
+
+// ReloadTLS reloads the certificate and key files used by Listen.
+func (s *Server) ReloadTLS() error {
+	s.mu.Lock()
+	reloader := s.certificates
+	s.mu.Unlock()
+
+	if reloader == nil {
+		return errors.New("web: server is not serving TLS")
+	}
+
+	return reloader.reload()
+}
+
+// Shutdown stops accepting connections and waits for open connections to
+// finish their current request, or until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stopped) })
+	return s.server.ShutdownWithContext(ctx)
 }
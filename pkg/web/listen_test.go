@@ -0,0 +1,238 @@
+package web
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed certificate for commonName and its
+// key to certFile and keyFile.
+func writeCertificate(t *testing.T, certFile, keyFile, commonName string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// freeAddr returns a loopback address with a port that was free a moment ago.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	return addr
+}
+
+// peerCommonName connects to addr over TLS and returns the common name of
+// the certificate the server presented, retrying until the server is up.
+func peerCommonName(t *testing.T, addr string) string {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+		if err == nil {
+			defer conn.Close()
+			return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("failed to connect to %s: %v", addr, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServerTimeouts(t *testing.T) {
+	server := New(Config{
+		ReadTimeout:        time.Second,
+		WriteTimeout:       2 * time.Second,
+		IdleTimeout:        3 * time.Second,
+		MaxRequestBodySize: 1024,
+		Concurrency:        10,
+	})
+
+	if server.server.ReadTimeout != time.Second || server.server.WriteTimeout != 2*time.Second || server.server.IdleTimeout != 3*time.Second {
+		t.Errorf("expected timeouts to be passed to fasthttp, got read %v, write %v, idle %v",
+			server.server.ReadTimeout, server.server.WriteTimeout, server.server.IdleTimeout)
+	}
+	if server.server.MaxRequestBodySize != 1024 || server.server.Concurrency != 10 {
+		t.Errorf("expected body size and concurrency limits to be passed to fasthttp, got %d and %d",
+			server.server.MaxRequestBodySize, server.server.Concurrency)
+	}
+}
+
+func TestShutdownDrainsRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	server := New()
+	server.Get("/slow", func(ctx *fasthttp.RequestCtx) {
+		close(started)
+		<-release
+		ctx.WriteString("done")
+	})
+
+	ln := fasthttputil.NewInmemoryListener()
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ln) }()
+
+	client := &fasthttp.Client{Dial: func(addr string) (net.Conn, error) { return ln.Dial() }}
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	response := make(chan result, 1)
+	go func() {
+		status, body, err := client.Get(nil, "http://web/slow")
+		response <- result{status, string(body), err}
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- server.Shutdown(context.Background()) }()
+
+	select {
+	case err := <-shutdown:
+		t.Fatalf("expected Shutdown to wait for the open request, returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	if err := <-shutdown; err != nil {
+		t.Errorf("unexpected shutdown error: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("unexpected serve error: %v", err)
+	}
+	if r := <-response; r.err != nil || r.status != fasthttp.StatusOK || r.body != "done" {
+		t.Errorf("expected the open request to complete, got %d %q %v", r.status, r.body, r.err)
+	}
+}
+
+func TestListenRequiresCertificateAndKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"Certificate without key", Config{CertFile: "server.crt"}},
+		{"Key without certificate", Config{KeyFile: "server.key"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := New(test.config).Listen("127.0.0.1:0"); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestReloadTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	writeCertificate(t, certFile, keyFile, "first")
+
+	if err := New().ReloadTLS(); err == nil {
+		t.Errorf("expected an error reloading TLS on a plain HTTP server")
+	}
+
+	addr := freeAddr(t)
+	server := New(Config{Addr: addr, CertFile: certFile, KeyFile: keyFile})
+	go func() { _ = server.Listen("") }()
+	t.Cleanup(func() { _ = server.Shutdown(context.Background()) })
+
+	if name := peerCommonName(t, addr); name != "first" {
+		t.Fatalf("expected certificate first, got %q", name)
+	}
+
+	writeCertificate(t, certFile, keyFile, "second")
+	if err := server.ReloadTLS(); err != nil {
+		t.Fatalf("unexpected reload error: %v", err)
+	}
+	if name := peerCommonName(t, addr); name != "second" {
+		t.Errorf("expected reloaded certificate second, got %q", name)
+	}
+}
+
+func TestCertificateReloaderWatch(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	writeCertificate(t, certFile, keyFile, "first")
+
+	reloader, err := newCertificateReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go reloader.watch(10*time.Millisecond, stop)
+
+	writeCertificate(t, certFile, keyFile, "second")
+	later := time.Now().Add(time.Minute)
+	for _, file := range []string{certFile, keyFile} {
+		if err := os.Chtimes(file, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		certificate, _ := reloader.getCertificate(nil)
+		leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if leaf.Subject.CommonName == "second" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the changed certificate to be reloaded, still serving %q", leaf.Subject.CommonName)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,43 @@
+package web
+
+import "github.com/valyala/fasthttp"
+
+// Middleware wraps a Handler. It may run code before and after calling next,
+// or abort the request by not calling next at all.
+type Middleware func(next Handler) Handler
+
+const abortedKey = "web.aborted"
+
+// Abort marks the request as aborted, handlers adapted with Before stop the
+// pipeline when they see this mark.
+func Abort(ctx *fasthttp.RequestCtx) {
+	ctx.SetUserValue(abortedKey, true)
+}
+
+// IsAborted reports whether Abort was called for the request.
+func IsAborted(ctx *fasthttp.RequestCtx) bool {
+	aborted, _ := ctx.UserValue(abortedKey).(bool)
+	return aborted
+}
+
+// Before adapts a plain handler into a middleware that runs it before the
+// rest of the pipeline, which continues unless the handler called Abort.
+func Before(handler Handler) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *fasthttp.RequestCtx) {
+			handler(ctx)
+			if IsAborted(ctx) {
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// chain wraps handler with middleware, the first middleware being the outermost.
+func chain(middleware []Middleware, handler Handler) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+	"log/slog"
+	"time"
+)
+
+// AccessLog logs one structured record per request with its method, path,
+// status, latency, response size, client IP and request ID. Requests are
+// logged through slog.Default() if logger is nil.
+func AccessLog(logger *slog.Logger) web.Middleware {
+	return func(next web.Handler) web.Handler {
+		return func(ctx *fasthttp.RequestCtx) {
+			start := time.Now()
+
+			next(ctx)
+
+			l := logger
+			if l == nil {
+				l = slog.Default()
+			}
+
+			status := ctx.Response.StatusCode()
+			level := slog.LevelInfo
+			if status >= fasthttp.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			l.LogAttrs(ctx, level, "http request",
+				slog.String("method", string(ctx.Method())),
+				slog.String("path", string(ctx.Path())),
+				slog.Int("status", status),
+				slog.Duration("latency", time.Since(start)),
+				slog.Int("bytes", len(ctx.Response.Body())),
+				slog.String("ip", ClientIP(ctx)),
+				slog.String("request_id", GetRequestID(ctx)),
+			)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+)
+
+// Compress compresses responses with brotli, gzip or deflate depending on the
+// request's Accept-Encoding header, using fasthttp's default levels.
+func Compress() web.Middleware {
+	return CompressLevel(fasthttp.CompressBrotliDefaultCompression, fasthttp.CompressDefaultCompression)
+}
+
+// CompressLevel is Compress with explicit brotli and gzip/deflate levels.
+func CompressLevel(brotliLevel, level int) web.Middleware {
+	return func(next web.Handler) web.Handler {
+		return web.Handler(fasthttp.CompressHandlerBrotliLevel(fasthttp.RequestHandler(next), brotliLevel, level))
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig configures the CORS middleware.
+type CORSConfig struct {
+	AllowOrigins     []string // Allowed origins, "*" allows any, defaults to "*"
+	AllowMethods     []string // Defaults to the common REST methods
+	AllowHeaders     []string // Defaults to the headers listed in the preflight request
+	ExposeHeaders    []string
+	AllowCredentials bool // Requires an explicit AllowOrigins list without "*"
+	MaxAge           int  // Seconds preflight responses may be cached, zero omits the header
+}
+
+// CORS answers preflight requests and adds the CORS headers to responses for
+// allowed origins. It should be registered with Server.Use so it also runs
+// for OPTIONS requests that have no route of their own. It panics if
+// AllowCredentials is combined with the "*" origin, which would let any site
+// make credentialed requests.
+func CORS(config ...CORSConfig) web.Middleware {
+	var cfg CORSConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if len(cfg.AllowOrigins) == 0 {
+		cfg.AllowOrigins = []string{"*"}
+	}
+	if cfg.AllowCredentials {
+		for _, origin := range cfg.AllowOrigins {
+			if origin == "*" {
+				panic(`middleware: CORS AllowCredentials requires explicit AllowOrigins, not "*"`)
+			}
+		}
+	}
+	if len(cfg.AllowMethods) == 0 {
+		cfg.AllowMethods = []string{
+			web.MethodGet, web.MethodHead, web.MethodPost, web.MethodPut,
+			web.MethodPatch, web.MethodDelete, web.MethodOptions,
+		}
+	}
+
+	allowMethods := strings.Join(cfg.AllowMethods, ", ")
+	allowHeaders := strings.Join(cfg.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposeHeaders, ", ")
+
+	return func(next web.Handler) web.Handler {
+		return func(ctx *fasthttp.RequestCtx) {
+			origin := string(ctx.Request.Header.Peek("Origin"))
+			if origin == "" {
+				next(ctx)
+				return
+			}
+
+			allowOrigin, ok := cfg.allowOrigin(origin)
+			preflight := ctx.IsOptions() && len(ctx.Request.Header.Peek("Access-Control-Request-Method")) > 0
+
+			if preflight {
+				ctx.Response.Header.Add("Vary", "Origin")
+				ctx.Response.Header.Add("Vary", "Access-Control-Request-Method")
+				ctx.Response.Header.Add("Vary", "Access-Control-Request-Headers")
+				if ok {
+					ctx.Response.Header.Set("Access-Control-Allow-Origin", allowOrigin)
+					ctx.Response.Header.Set("Access-Control-Allow-Methods", allowMethods)
+
+					headers := allowHeaders
+					if headers == "" {
+						headers = string(ctx.Request.Header.Peek("Access-Control-Request-Headers"))
+					}
+					if headers != "" {
+						ctx.Response.Header.Set("Access-Control-Allow-Headers", headers)
+					}
+					if cfg.AllowCredentials {
+						ctx.Response.Header.Set("Access-Control-Allow-Credentials", "true")
+					}
+					if cfg.MaxAge > 0 {
+						ctx.Response.Header.Set("Access-Control-Max-Age", strconv.Itoa(cfg.MaxAge))
+					}
+				}
+				ctx.SetStatusCode(fasthttp.StatusNoContent)
+				return
+			}
+
+			next(ctx)
+
+			if !ok {
+				return
+			}
+			ctx.Response.Header.Add("Vary", "Origin")
+			ctx.Response.Header.Set("Access-Control-Allow-Origin", allowOrigin)
+			if cfg.AllowCredentials {
+				ctx.Response.Header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if exposeHeaders != "" {
+				ctx.Response.Header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+		}
+	}
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin.
+func (c CORSConfig) allowOrigin(origin string) (string, bool) {
+	for _, allowed := range c.AllowOrigins {
+		if allowed == "*" {
+			return "*", true
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+	"net"
+	"testing"
+	"time"
+)
+
+func serve(server *web.Server, method, uri string, headers map[string]string) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.SetRequestURI(uri)
+	req.Header.SetMethod(method)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(&req, &net.TCPAddr{IP: net.ParseIP("10.0.0.1")}, nil)
+
+	server.Handler()(ctx)
+	return ctx
+}
+
+func TestRecoverAndRequestID(t *testing.T) {
+	server := web.New()
+	server.Use(RequestID(), Recover())
+	server.Get("/panic", func(ctx *fasthttp.RequestCtx) { panic("boom") })
+
+	ctx := serve(server, web.MethodGet, "/panic", nil)
+	if code := ctx.Response.StatusCode(); code != fasthttp.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", code)
+	}
+	if id := string(ctx.Response.Header.Peek(RequestIDHeader)); id == "" {
+		t.Errorf("expected a generated request ID")
+	}
+
+	ctx = serve(server, web.MethodGet, "/panic", map[string]string{RequestIDHeader: "abc"})
+	if id := string(ctx.Response.Header.Peek(RequestIDHeader)); id != "abc" {
+		t.Errorf("expected propagated request ID abc, got %q", id)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	server := web.New()
+	server.Use(CORS(CORSConfig{AllowOrigins: []string{"https://app.example.com"}, MaxAge: 600}))
+	server.Get("/items", func(ctx *fasthttp.RequestCtx) { ctx.WriteString("items") })
+
+	ctx := serve(server, web.MethodOptions, "/items", map[string]string{
+		"Origin":                         "https://app.example.com",
+		"Access-Control-Request-Method":  "GET",
+		"Access-Control-Request-Headers": "Authorization",
+	})
+	if code := ctx.Response.StatusCode(); code != fasthttp.StatusNoContent {
+		t.Errorf("expected status 204, got %d", code)
+	}
+	if origin := string(ctx.Response.Header.Peek("Access-Control-Allow-Origin")); origin != "https://app.example.com" {
+		t.Errorf("expected allowed origin, got %q", origin)
+	}
+	if headers := string(ctx.Response.Header.Peek("Access-Control-Allow-Headers")); headers != "Authorization" {
+		t.Errorf("expected requested headers to be allowed, got %q", headers)
+	}
+
+	ctx = serve(server, web.MethodGet, "/items", map[string]string{"Origin": "https://evil.example.com"})
+	if origin := ctx.Response.Header.Peek("Access-Control-Allow-Origin"); origin != nil {
+		t.Errorf("expected no CORS headers for disallowed origin, got %q", origin)
+	}
+}
+
+func TestCORSCredentialsWithWildcard(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+	}{
+		{"Default origins", nil},
+		{"Explicit wildcard", []string{"https://app.example.com", "*"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a panic for credentials with origins %v", test.origins)
+				}
+			}()
+			CORS(CORSConfig{AllowOrigins: test.origins, AllowCredentials: true})
+		})
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trusted    []string
+		forwarded  string
+		expectedIP string
+	}{
+		{"Untrusted peer", nil, "1.2.3.4", "10.0.0.1"},
+		{"Trusted peer", []string{"10.0.0.0/8"}, "1.2.3.4, 10.0.0.2", "1.2.3.4"},
+		{"Spoofed left-most hop", []string{"10.0.0.1"}, "6.6.6.6, 1.2.3.4", "1.2.3.4"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ip string
+			server := web.New()
+			server.Use(RealIP(RealIPConfig{TrustedProxies: test.trusted}))
+			server.Get("/", func(ctx *fasthttp.RequestCtx) { ip = ClientIP(ctx) })
+
+			serve(server, web.MethodGet, "/", map[string]string{"X-Forwarded-For": test.forwarded})
+
+			if ip != test.expectedIP {
+				t.Errorf("expected %s, got %s", test.expectedIP, ip)
+			}
+		})
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	now := time.Unix(0, 0)
+	store.now = func() time.Time { return now }
+
+	server := web.New()
+	server.Get("/items", func(ctx *fasthttp.RequestCtx) { ctx.WriteString("items") },
+		RateLimiter(RateLimitConfig{
+			Limit:   RateLimit{Requests: 2, Window: time.Minute},
+			KeyFunc: KeyByHeader("X-API-Key"),
+			Store:   store,
+		}))
+
+	tests := []struct {
+		key               string
+		advance           time.Duration
+		expectedStatus    int
+		expectedRemaining string
+		expectedRetry     string
+	}{
+		{key: "a", expectedStatus: fasthttp.StatusOK, expectedRemaining: "1"},
+		{key: "a", expectedStatus: fasthttp.StatusOK, expectedRemaining: "0"},
+		{key: "a", expectedStatus: fasthttp.StatusTooManyRequests, expectedRemaining: "0", expectedRetry: "30"},
+		{key: "b", expectedStatus: fasthttp.StatusOK, expectedRemaining: "1"},
+		{key: "a", advance: 30 * time.Second, expectedStatus: fasthttp.StatusOK, expectedRemaining: "0"},
+	}
+
+	for i, test := range tests {
+		now = now.Add(test.advance)
+		ctx := serve(server, web.MethodGet, "/items", map[string]string{"X-API-Key": test.key})
+
+		if code := ctx.Response.StatusCode(); code != test.expectedStatus {
+			t.Errorf("request %d: expected status %d, got %d", i, test.expectedStatus, code)
+		}
+		if remaining := string(ctx.Response.Header.Peek("RateLimit-Remaining")); remaining != test.expectedRemaining {
+			t.Errorf("request %d: expected remaining %s, got %q", i, test.expectedRemaining, remaining)
+		}
+		if limit := string(ctx.Response.Header.Peek("RateLimit-Limit")); limit != "2" {
+			t.Errorf("request %d: expected limit 2, got %q", i, limit)
+		}
+		if retry := string(ctx.Response.Header.Peek("Retry-After")); retry != test.expectedRetry {
+			t.Errorf("request %d: expected Retry-After %q, got %q", i, test.expectedRetry, retry)
+		}
+	}
+}
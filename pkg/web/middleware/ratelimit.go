@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit allows Requests per Window, refilled continuously, with bursts of
+// up to Burst requests.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+	Burst    int // Defaults to Requests
+}
+
+// RateLimitResult is the state of a key's bucket after taking a request.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // Until the bucket is full again
+	RetryAfter time.Duration // Until the next request is allowed, zero if allowed
+}
+
+// RateLimitStore keeps the rate limit state of each key. Implement it on top
+// of a shared backend such as Redis to limit across several instances.
+type RateLimitStore interface {
+	Take(key string, limit RateLimit) (RateLimitResult, error)
+}
+
+// RateLimitConfig configures the RateLimiter middleware.
+type RateLimitConfig struct {
+	Limit   RateLimit
+	KeyFunc func(ctx *fasthttp.RequestCtx) string // Defaults to KeyByIP
+	Store   RateLimitStore                        // Defaults to a new MemoryRateLimitStore
+	Prefix  string                                // Namespaces keys when several limiters share a Store
+}
+
+// KeyByIP keys requests by ClientIP.
+func KeyByIP(ctx *fasthttp.RequestCtx) string {
+	return ClientIP(ctx)
+}
+
+// KeyByHeader keys requests by the value of a header such as an API key,
+// falling back to ClientIP for requests without it.
+func KeyByHeader(name string) func(ctx *fasthttp.RequestCtx) string {
+	return func(ctx *fasthttp.RequestCtx) string {
+		if value := ctx.Request.Header.Peek(name); len(value) > 0 {
+			return name + ":" + string(value)
+		}
+		return ClientIP(ctx)
+	}
+}
+
+// RateLimiter limits requests per key, answering requests over the limit with
+// 429 and a Retry-After header. Every response carries the RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers. Register it with
+// Server.Use or a Group for a shared limit, or on a single route for a
+// per-route limit. Store errors are logged and the request is let through.
+func RateLimiter(config RateLimitConfig) web.Middleware {
+	if config.Limit.Requests <= 0 || config.Limit.Window <= 0 {
+		panic("middleware: rate limit requires positive Requests and Window")
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = KeyByIP
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryRateLimitStore()
+	}
+
+	return func(next web.Handler) web.Handler {
+		return func(ctx *fasthttp.RequestCtx) {
+			result, err := config.Store.Take(config.Prefix+config.KeyFunc(ctx), config.Limit)
+			if err != nil {
+				log.Printf("rate limit store error: %v", err)
+				next(ctx)
+				return
+			}
+
+			if !result.Allowed {
+				web.JSONError(ctx, fasthttp.StatusTooManyRequests, web.NewHTTPError(fasthttp.StatusTooManyRequests, "rate limit exceeded"))
+				ctx.Response.Header.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				setRateLimitHeaders(ctx, result)
+				return
+			}
+
+			next(ctx)
+
+			// Error responses reset the headers, so set them after the handler.
+			setRateLimitHeaders(ctx, result)
+		}
+	}
+}
+
+func setRateLimitHeaders(ctx *fasthttp.RequestCtx, result RateLimitResult) {
+	ctx.Response.Header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	ctx.Response.Header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	ctx.Response.Header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// MemoryRateLimitStore is a RateLimitStore holding token buckets in memory.
+// Full buckets are evicted periodically, so idle keys do not accumulate.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // When the bucket is full again and can be evicted
+}
+
+// NewMemoryRateLimitStore returns an empty in-memory store.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Take refills key's bucket for the time elapsed since the last request and
+// takes a token from it if one is available.
+func (s *MemoryRateLimitStore) Take(key string, limit RateLimit) (RateLimitResult, error) {
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = limit.Requests
+	}
+	rate := float64(limit.Requests) / limit.Window.Seconds() // Tokens per second
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now, limit.Window)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(burst), last: now}
+		s.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(float64(burst), bucket.tokens+now.Sub(bucket.last).Seconds()*rate)
+	bucket.last = now
+
+	result := RateLimitResult{Limit: burst}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsDuration((1 - bucket.tokens) / rate)
+	}
+
+	result.Remaining = int(bucket.tokens)
+	result.Reset = secondsDuration((float64(burst) - bucket.tokens) / rate)
+	bucket.full = now.Add(result.Reset)
+
+	return result, nil
+}
+
+// sweep evicts full buckets, at most once per window.
+func (s *MemoryRateLimitStore) sweep(now time.Time, window time.Duration) {
+	if now.Sub(s.lastSweep) < window {
+		return
+	}
+	s.lastSweep = now
+
+	for key, bucket := range s.buckets {
+		if !now.Before(bucket.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
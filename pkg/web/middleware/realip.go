@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+	"log"
+	"net"
+	"strings"
+)
+
+const clientIPKey = "middleware.client_ip"
+
+// RealIPConfig configures the RealIP middleware.
+type RealIPConfig struct {
+	// TrustedProxies lists the IPs or CIDRs of proxies whose forwarding
+	// headers are trusted. Headers from other peers are ignored.
+	TrustedProxies []string
+}
+
+// RealIP determines the client IP from the X-Forwarded-For and X-Real-IP
+// headers set by trusted proxies, readable with ClientIP.
+func RealIP(config RealIPConfig) web.Middleware {
+	var trusted []*net.IPNet
+	for _, proxy := range config.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if strings.Contains(proxy, ":") {
+				proxy += "/128"
+			} else {
+				proxy += "/32"
+			}
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			log.Printf("invalid trusted proxy %q: %v", proxy, err)
+			continue
+		}
+		trusted = append(trusted, network)
+	}
+
+	isTrusted := func(ip net.IP) bool {
+		for _, network := range trusted {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next web.Handler) web.Handler {
+		return func(ctx *fasthttp.RequestCtx) {
+			ip := ctx.RemoteIP()
+
+			if isTrusted(ip) {
+				ip = forwardedIP(ctx, ip, isTrusted)
+			}
+
+			ctx.SetUserValue(clientIPKey, ip.String())
+			next(ctx)
+		}
+	}
+}
+
+// forwardedIP walks X-Forwarded-For from the right, skipping trusted proxies,
+// and falls back to X-Real-IP.
+func forwardedIP(ctx *fasthttp.RequestCtx, remote net.IP, isTrusted func(net.IP) bool) net.IP {
+	if forwarded := string(ctx.Request.Header.Peek("X-Forwarded-For")); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+			remote = ip
+			if !isTrusted(ip) {
+				return ip
+			}
+		}
+		return remote
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(string(ctx.Request.Header.Peek("X-Real-IP")))); ip != nil {
+		return ip
+	}
+
+	return remote
+}
+
+// ClientIP returns the client IP determined by RealIP, or the peer address if
+// RealIP is not in use.
+func ClientIP(ctx *fasthttp.RequestCtx) string {
+	if ip, ok := ctx.UserValue(clientIPKey).(string); ok {
+		return ip
+	}
+	return ctx.RemoteIP().String()
+}
@@ -0,0 +1,28 @@
+// Package middleware provides standard middleware for web.Server.
+package middleware
+
+import (
+	"fmt"
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+	"log"
+	"runtime/debug"
+)
+
+// Recover turns panics in later middleware and handlers into 500 responses
+// instead of crashing the connection, logging the panic with its stack trace.
+func Recover() web.Middleware {
+	return func(next web.Handler) web.Handler {
+		return func(ctx *fasthttp.RequestCtx) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("panic occurred: %v\n%s", r, debug.Stack())
+					ctx.Response.Reset()
+					web.JSONError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("panic: %v", r))
+				}
+			}()
+
+			next(ctx)
+		}
+	}
+}
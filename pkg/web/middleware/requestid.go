@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"github.com/google/uuid"
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	requestIDKey    = "middleware.request_id"
+)
+
+// RequestID propagates the X-Request-ID header of the request, generating one
+// if it is missing, and echoes it on the response. The ID is also written
+// back to the request headers so handlers forwarding headers (e.g. to RPC
+// context data) pass it along.
+func RequestID() web.Middleware {
+	return func(next web.Handler) web.Handler {
+		return func(ctx *fasthttp.RequestCtx) {
+			id := string(ctx.Request.Header.Peek(RequestIDHeader))
+			if id == "" {
+				id = uuid.New().String()
+				ctx.Request.Header.Set(RequestIDHeader, id)
+			}
+
+			ctx.SetUserValue(requestIDKey, id)
+			ctx.Response.Header.Set(RequestIDHeader, id)
+
+			next(ctx)
+
+			// Error responses reset the headers, so set it again.
+			ctx.Response.Header.Set(RequestIDHeader, id)
+		}
+	}
+}
+
+// GetRequestID returns the request ID assigned by RequestID, or "".
+func GetRequestID(ctx *fasthttp.RequestCtx) string {
+	id, _ := ctx.UserValue(requestIDKey).(string)
+	return id
+}
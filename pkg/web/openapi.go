@@ -0,0 +1,441 @@
+package web
+
+import (
+	"github.com/valyala/fasthttp"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const openAPIVersion = "3.0.3"
+
+// RouteDoc documents a route in the generated OpenAPI document.
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	OperationID string
+	Deprecated  bool
+	Hidden      bool // Leave the route out of the document
+
+	// Request and Response are values of the request and response types.
+	// The JSON body schema is derived from their types by reflection, and
+	// Request fields tagged `path:"..."` or `query:"..."` become parameters.
+	Request  interface{}
+	Response interface{}
+	Status   int // Success status, defaults to 200
+
+	Params []ParamDoc // Additional or overriding parameter documentation
+}
+
+// ParamDoc documents a path, query or header parameter.
+type ParamDoc struct {
+	Name        string
+	In          string // "path", "query" or "header"
+	Description string
+	Required    bool
+	Type        string // JSON schema type, defaults to "string"
+}
+
+// TypedDoc returns doc with Request and Response set to the types fn takes
+// and returns. The types are inferred from the handler passed to Typed, so
+// the document cannot drift from it:
+//
+//	server.Post("/items", web.Typed(createItem)).Describe(web.TypedDoc(createItem, web.RouteDoc{Summary: "Create an item"}))
+func TypedDoc[Req, Resp any](fn TypedHandlerFunc[Req, Resp], doc RouteDoc) RouteDoc {
+	var req Req
+	var resp Resp
+	doc.Request = req
+	doc.Response = resp
+	return doc
+}
+
+// OpenAPIInfo is the info object of the OpenAPI document.
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIDocument is a generated OpenAPI 3 document.
+type OpenAPIDocument struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       OpenAPIInfo                     `json:"info"`
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components components                      `json:"components"`
+}
+
+type components struct {
+	Schemas map[string]*schema `json:"schemas,omitempty"`
+}
+
+type operation struct {
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	OperationID string               `json:"operationId,omitempty"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Parameters  []parameter          `json:"parameters,omitempty"`
+	RequestBody *requestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*response `json:"responses"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+}
+
+// Describe attaches documentation to the most recently registered route.
+func (s *Server) Describe(doc RouteDoc) Router {
+	if len(s.routes) > 0 {
+		s.routes[len(s.routes)-1].Doc = &doc
+	}
+	return s
+}
+
+// OpenAPI generates an OpenAPI document from the registered routes.
+func (s *Server) OpenAPI(info OpenAPIInfo) *OpenAPIDocument {
+	gen := &schemaGenerator{schemas: make(map[string]*schema), names: make(map[reflect.Type]string)}
+	doc := &OpenAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info:    info,
+		Paths:   make(map[string]map[string]operation),
+	}
+
+	for _, route := range s.routes {
+		if route.Doc != nil && route.Doc.Hidden {
+			continue
+		}
+
+		path := openAPIPath(route.Path)
+		for _, method := range route.Methods {
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = make(map[string]operation)
+			}
+			doc.Paths[path][strings.ToLower(method)] = gen.operation(route, method)
+		}
+	}
+
+	doc.Components.Schemas = gen.schemas
+	return doc
+}
+
+// ServeOpenAPI registers a GET route at path serving the OpenAPI document as
+// JSON. The document is generated on each request, so it includes routes
+// registered later.
+func (s *Server) ServeOpenAPI(path string, info OpenAPIInfo, middleware ...Middleware) Router {
+	s.Get(path, func(ctx *fasthttp.RequestCtx) {
+		if err := JSON(ctx, fasthttp.StatusOK, s.OpenAPI(info)); err != nil {
+			JSONError(ctx, fasthttp.StatusInternalServerError, err)
+		}
+	}, middleware...)
+	return s.Describe(RouteDoc{Hidden: true})
+}
+
+// openAPIPath converts /users/:id and /files/*path to /users/{id} and /files/{path}.
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+type schemaGenerator struct {
+	schemas map[string]*schema
+	names   map[reflect.Type]string // Component names of the types in schemas
+}
+
+func (g *schemaGenerator) operation(route *Route, method string) operation {
+	doc := RouteDoc{}
+	if route.Doc != nil {
+		doc = *route.Doc
+	}
+
+	op := operation{
+		Summary:     doc.Summary,
+		Description: doc.Description,
+		Tags:        doc.Tags,
+		OperationID: doc.OperationID,
+		Deprecated:  doc.Deprecated,
+		Parameters:  g.parameters(route.Path, doc),
+		Responses:   make(map[string]*response),
+	}
+
+	if body := g.bodySchema(doc.Request); body != nil && method != MethodGet && method != MethodHead && method != MethodDelete {
+		op.RequestBody = &requestBody{
+			Required: true,
+			Content:  map[string]mediaType{"application/json": {Schema: body}},
+		}
+	}
+
+	status := doc.Status
+	if status == 0 {
+		status = fasthttp.StatusOK
+	}
+	success := &response{Description: fasthttp.StatusMessage(status)}
+	if doc.Response != nil {
+		success.Content = map[string]mediaType{"application/json": {Schema: g.schema(reflect.TypeOf(doc.Response))}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+	op.Responses["default"] = &response{
+		Description: "Error",
+		Content:     map[string]mediaType{ContentTypeProblemJSON: {Schema: g.schema(reflect.TypeOf(Problem{}))}},
+	}
+
+	return op
+}
+
+// parameters collects the path parameters of the pattern, the path and query
+// fields of the request type and the documented params, later ones winning.
+func (g *schemaGenerator) parameters(path string, doc RouteDoc) []parameter {
+	var params []parameter
+	index := make(map[string]int)
+
+	add := func(p parameter) {
+		key := p.In + ":" + p.Name
+		if i, ok := index[key]; ok {
+			params[i] = p
+			return
+		}
+		index[key] = len(params)
+		params = append(params, p)
+	}
+
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			add(parameter{Name: segment[1:], In: "path", Required: true, Schema: &schema{Type: "string"}})
+		}
+	}
+
+	if t := structType(doc.Request); t != nil {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			for _, in := range []string{"path", "query"} {
+				if name := field.Tag.Get(in); name != "" && name != "-" {
+					add(parameter{Name: name, In: in, Required: in == "path", Schema: g.schema(field.Type)})
+				}
+			}
+		}
+	}
+
+	for _, p := range doc.Params {
+		paramType := p.Type
+		if paramType == "" {
+			paramType = "string"
+		}
+		add(parameter{
+			Name:        p.Name,
+			In:          p.In,
+			Description: p.Description,
+			Required:    p.Required || p.In == "path",
+			Schema:      &schema{Type: paramType},
+		})
+	}
+
+	return params
+}
+
+// bodySchema returns the JSON body schema of the request value, leaving out
+// fields bound from the path or query string.
+func (g *schemaGenerator) bodySchema(request interface{}) *schema {
+	if request == nil {
+		return nil
+	}
+
+	t := structType(request)
+	if t == nil {
+		return g.schema(reflect.TypeOf(request))
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.IsExported() && field.Tag.Get("path") == "" && field.Tag.Get("query") == "" && field.Tag.Get("json") != "-" {
+			return g.schema(reflect.TypeOf(request))
+		}
+	}
+
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema returns the schema of t. Named structs are added to the components
+// and referenced, which also handles recursive types.
+func (g *schemaGenerator) schema(t reflect.Type) *schema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var s *schema
+	switch {
+	case t == timeType:
+		s = &schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		name, ok := g.names[t]
+		if !ok {
+			name = g.schemaName(t)
+			g.names[t] = name
+			g.schemas[name] = &schema{} // Placeholder for recursive references
+			g.schemas[name] = g.structSchema(t)
+		}
+		s = &schema{Ref: "#/components/schemas/" + name}
+		// $ref siblings are ignored, so nullable is dropped for references.
+		return s
+	case t.Kind() == reflect.Struct:
+		s = g.structSchema(t)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		s = &schema{Type: "string", Format: "byte"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		s = &schema{Type: "array", Items: g.schema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		s = &schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case t.Kind() == reflect.String:
+		s = &schema{Type: "string"}
+	case t.Kind() == reflect.Bool:
+		s = &schema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		s = &schema{Type: "integer"}
+		if t.Kind() == reflect.Int64 || t.Kind() == reflect.Uint64 {
+			s.Format = "int64"
+		}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		s = &schema{Type: "number"}
+	default:
+		s = &schema{}
+	}
+
+	s.Nullable = nullable
+	return s
+}
+
+// schemaName returns a component name for t made of its package name and type
+// name, e.g. "orders.Item", or "orders.Page_orders.Item" for generic types.
+// If another type already has that name, the full package path is used.
+func (g *schemaGenerator) schemaName(t reflect.Type) string {
+	pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+	name := sanitizeSchemaName(pkg + "." + typeArgPackages.ReplaceAllString(t.Name(), ""))
+	if _, taken := g.schemas[name]; !taken {
+		return name
+	}
+
+	base := sanitizeSchemaName(t.PkgPath() + "." + t.Name())
+	name = base
+	for i := 2; ; i++ {
+		if _, taken := g.schemas[name]; !taken {
+			return name
+		}
+		name = base + "_" + strconv.Itoa(i)
+	}
+}
+
+// typeArgPackages matches the package path before the package name of type
+// arguments, e.g. "github.com/acme/" in "Page[github.com/acme/orders.Item]".
+var typeArgPackages = regexp.MustCompile(`[^\[\],*\s]*/`)
+
+// sanitizeSchemaName replaces the characters component names may not contain.
+func sanitizeSchemaName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	return strings.Trim(name, "_")
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *schema {
+	s := &schema{Type: "object", Properties: make(map[string]*schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Tag.Get("path") != "" || field.Tag.Get("query") != "" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				inner := g.structSchema(embedded)
+				for key, value := range inner.Properties {
+					s.Properties[key] = value
+				}
+				s.Required = append(s.Required, inner.Required...)
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		s.Properties[name] = g.schema(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	sort.Strings(s.Required)
+	return s
+}
+
+// structType returns the struct type of v, dereferencing pointers, or nil.
+func structType(v interface{}) reflect.Type {
+	if v == nil {
+		return nil
+	}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
@@ -0,0 +1,138 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/joejoe-am/namego/pkg/rpc"
+	"github.com/valyala/fasthttp"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestOpenAPI(t *testing.T) {
+	server := New()
+	server.ServeOpenAPI("/openapi.json", OpenAPIInfo{Title: "Items", Version: "1.0.0"})
+	createItem := func(ctx *fasthttp.RequestCtx, req createItemRequest) (createItemResponse, error) {
+		return createItemResponse{}, nil
+	}
+	server.Group("/api").
+		Post("/items/:id", Typed(createItem)).
+		Describe(TypedDoc(createItem, RouteDoc{Summary: "Create an item", Status: fasthttp.StatusCreated}))
+	server.Get("/health", func(ctx *fasthttp.RequestCtx) {})
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/openapi.json")
+	ctx.Request.Header.SetMethod(MethodGet)
+	server.Handler()(ctx)
+
+	var doc struct {
+		Paths map[string]map[string]struct {
+			Summary    string `json:"summary"`
+			Parameters []struct {
+				Name string `json:"name"`
+				In   string `json:"in"`
+			} `json:"parameters"`
+			RequestBody *struct {
+				Content map[string]struct {
+					Schema struct {
+						Ref string `json:"$ref"`
+					} `json:"schema"`
+				} `json:"content"`
+			} `json:"requestBody"`
+			Responses map[string]interface{} `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(ctx.Response.Body(), &doc); err != nil {
+		t.Fatalf("invalid document: %v", err)
+	}
+
+	if _, ok := doc.Paths["/openapi.json"]; ok {
+		t.Errorf("expected the document route to be hidden")
+	}
+	if _, ok := doc.Paths["/health"]["get"]; !ok {
+		t.Errorf("expected undocumented routes to be listed")
+	}
+
+	op, ok := doc.Paths["/api/items/{id}"]["post"]
+	if !ok {
+		t.Fatalf("expected /api/items/{id} to be documented, got %v", doc.Paths)
+	}
+	if op.Summary != "Create an item" {
+		t.Errorf("expected summary, got %q", op.Summary)
+	}
+	if len(op.Parameters) != 3 {
+		t.Errorf("expected id, tag and notify parameters, got %+v", op.Parameters)
+	}
+	if op.RequestBody == nil || op.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/web.createItemRequest" {
+		t.Errorf("expected request body reference, got %+v", op.RequestBody)
+	}
+	if _, ok := op.Responses["201"]; !ok {
+		t.Errorf("expected 201 response, got %v", op.Responses)
+	}
+
+	properties := doc.Components.Schemas["web.createItemRequest"].Properties
+	if _, ok := properties["name"]; !ok || len(properties) != 1 {
+		t.Errorf("expected only the body field in the request schema, got %v", properties)
+	}
+}
+
+type Response struct {
+	ID int `json:"id"`
+}
+
+type page[T any] struct {
+	Items []T `json:"items"`
+}
+
+func TestSchemaNames(t *testing.T) {
+	tests := []struct {
+		name          string
+		types         []reflect.Type
+		taken         []string
+		expectedNames []string
+	}{
+		{
+			name:          "Same type name in different packages",
+			types:         []reflect.Type{reflect.TypeOf(Response{}), reflect.TypeOf(rpc.Response{})},
+			expectedNames: []string{"web.Response", "rpc.Response", "rpc.RemoteError"},
+		},
+		{
+			name:          "Generic type",
+			types:         []reflect.Type{reflect.TypeOf(page[map[string]Response]{})},
+			expectedNames: []string{"web.page_map_string_web.Response", "web.Response"},
+		},
+		{
+			name:          "Package name taken",
+			types:         []reflect.Type{reflect.TypeOf(rpc.Response{})},
+			taken:         []string{"rpc.Response"},
+			expectedNames: []string{"github.com_joejoe-am_namego_pkg_rpc.Response", "rpc.RemoteError"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gen := &schemaGenerator{schemas: make(map[string]*schema), names: make(map[reflect.Type]string)}
+			for _, name := range test.taken {
+				gen.schemas[name] = &schema{}
+			}
+			for _, typ := range test.types {
+				gen.schema(typ)
+			}
+
+			var names []string
+			for _, name := range gen.names {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			sort.Strings(test.expectedNames)
+			if !reflect.DeepEqual(names, test.expectedNames) {
+				t.Errorf("expected components %v, got %v", test.expectedNames, names)
+			}
+		})
+	}
+}
@@ -2,36 +2,51 @@ package web
 
 import (
 	"github.com/valyala/fasthttp"
-	"strings"
 )
 
 type Router interface {
-	Add(methods []string, path string, handler Handler, middleware ...Handler) Router
+	Add(methods []string, path string, handler Handler, middleware ...Middleware) Router
 
-	Get(path string, handler Handler, middleware ...Handler) Router
-	Post(path string, handler Handler, middleware ...Handler) Router
-	Put(path string, handler Handler, middleware ...Handler) Router
-	Delete(path string, handler Handler, middleware ...Handler) Router
-	Patch(path string, handler Handler, middleware ...Handler) Router
+	Get(path string, handler Handler, middleware ...Middleware) Router
+	Head(path string, handler Handler, middleware ...Middleware) Router
+	Post(path string, handler Handler, middleware ...Middleware) Router
+	Put(path string, handler Handler, middleware ...Middleware) Router
+	Delete(path string, handler Handler, middleware ...Middleware) Router
+	Patch(path string, handler Handler, middleware ...Middleware) Router
+	Options(path string, handler Handler, middleware ...Middleware) Router
+
+	Use(middleware ...Middleware) Router
+	Group(prefix string, middleware ...Middleware) Router
+	Route(prefix string, fn func(router Router), middleware ...Middleware) Router
+
+	// Describe attaches documentation to the most recently registered route.
+	Describe(doc RouteDoc) Router
 }
 
+// Route describes a registered path pattern. Patterns may contain named
+// parameters (/users/:id) and a trailing wildcard (/files/*path).
 type Route struct {
 	Methods    []string
 	Path       string
 	Handler    Handler
-	Middleware []Handler // Middleware handlers
+	Middleware []Middleware // Route middleware, run after global and group middleware
+	Doc        *RouteDoc    // OpenAPI documentation, see Describe
+
+	pipeline Handler // Handler wrapped with Middleware
+}
+
+const routePatternKey = "web.route"
+
+// RoutePattern returns the path pattern of the route matching the request,
+// e.g. "/users/:id", or "" if no route matched.
+func RoutePattern(ctx *fasthttp.RequestCtx) string {
+	pattern, _ := ctx.UserValue(routePatternKey).(string)
+	return pattern
 }
 
-// matchRoute checks if the incoming request matches a registered route
-func matchRoute(ctx *fasthttp.RequestCtx, route Route) bool {
-	// Check if the HTTP method matches (case-insensitive)
-	for _, method := range route.Methods {
-		if strings.EqualFold(method, string(ctx.Method())) {
-			// Check if the path matches
-			if route.Path == string(ctx.Path()) {
-				return true
-			}
-		}
-	}
-	return false
+// Param returns the value of the named path parameter for the current request,
+// or "" if the matched route has no such parameter.
+func Param(ctx *fasthttp.RequestCtx, name string) string {
+	value, _ := ctx.UserValue(name).(string)
+	return value
 }
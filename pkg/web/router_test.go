@@ -8,68 +8,275 @@ import (
 func TestMatchRoute(t *testing.T) {
 	tests := []struct {
 		name           string
-		route          Route
+		methods        []string
+		path           string
 		requestMethod  string
 		requestPath    string
 		expectedResult bool
+		expectedParams map[string]string
 	}{
 		{
-			name: "Exact match - GET /health",
-			route: Route{
-				Methods: []string{"GET", "POST"},
-				Path:    "/health",
-			},
+			name:           "Exact match - GET /health",
+			methods:        []string{"GET", "POST"},
+			path:           "/health",
 			requestMethod:  "GET",
 			requestPath:    "/health",
 			expectedResult: true,
 		},
 		{
-			name: "Method mismatch - POST /health",
-			route: Route{
-				Methods: []string{"GET"},
-				Path:    "/health",
-			},
+			name:           "Method mismatch - POST /health",
+			methods:        []string{"GET"},
+			path:           "/health",
 			requestMethod:  "POST",
 			requestPath:    "/health",
 			expectedResult: false,
 		},
 		{
-			name: "Path mismatch - GET /status",
-			route: Route{
-				Methods: []string{"GET"},
-				Path:    "/health",
-			},
+			name:           "Path mismatch - GET /status",
+			methods:        []string{"GET"},
+			path:           "/health",
 			requestMethod:  "GET",
 			requestPath:    "/status",
 			expectedResult: false,
 		},
 		{
-			name: "Case insensitive match - GET /Health",
-			route: Route{
-				Methods: []string{"get"},
-				Path:    "/health",
-			},
+			name:           "Case insensitive match - GET /Health",
+			methods:        []string{"get"},
+			path:           "/health",
 			requestMethod:  "GET",
 			requestPath:    "/Health",
 			expectedResult: false,
 		},
+		{
+			name:           "Named parameter - GET /users/42",
+			methods:        []string{"GET"},
+			path:           "/users/:id",
+			requestMethod:  "GET",
+			requestPath:    "/users/42",
+			expectedResult: true,
+			expectedParams: map[string]string{"id": "42"},
+		},
+		{
+			name:           "Empty parameter - GET /users/",
+			methods:        []string{"GET"},
+			path:           "/users/:id",
+			requestMethod:  "GET",
+			requestPath:    "/users/",
+			expectedResult: false,
+		},
+		{
+			name:           "Wildcard - GET /files/a/b.txt",
+			methods:        []string{"GET"},
+			path:           "/files/*path",
+			requestMethod:  "GET",
+			requestPath:    "/files/a/b.txt",
+			expectedResult: true,
+			expectedParams: map[string]string{"path": "a/b.txt"},
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			server := New()
+			server.Add(test.methods, test.path, func(ctx *fasthttp.RequestCtx) {})
+
 			// Mock the request context
 			ctx := &fasthttp.RequestCtx{}
 			ctx.Request.SetRequestURI(test.requestPath)
 			ctx.Request.Header.SetMethod(test.requestMethod)
 
-			// Perform the matchRoute test
-			result := matchRoute(ctx, test.route)
+			route, _ := server.match(ctx, string(ctx.Path()))
+			result := route != nil
 
 			// Validate the result
 			if result != test.expectedResult {
 				t.Errorf("expected %v, got %v", test.expectedResult, result)
 			}
+			for key, value := range test.expectedParams {
+				if param := Param(ctx, key); param != value {
+					t.Errorf("expected param %s=%q, got %q", key, value, param)
+				}
+			}
 		})
 	}
 
 }
+
+func TestMatchRoutePriority(t *testing.T) {
+	server := New()
+	server.Get("/users/me", func(ctx *fasthttp.RequestCtx) { ctx.WriteString("me") })
+	server.Get("/users/:id", func(ctx *fasthttp.RequestCtx) { ctx.WriteString("id") })
+	server.Get("/users/:id/posts", func(ctx *fasthttp.RequestCtx) { ctx.WriteString("posts") })
+
+	for path, expected := range map[string]string{
+		"/users/me":       "me",
+		"/users/42":       "id",
+		"/users/me/posts": "posts",
+	} {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI(path)
+		ctx.Request.Header.SetMethod(MethodGet)
+
+		server.Handler()(ctx)
+
+		if body := string(ctx.Response.Body()); body != expected {
+			t.Errorf("%s: expected %q, got %q", path, expected, body)
+		}
+	}
+}
+
+func TestRedirectTrailingSlash(t *testing.T) {
+	server := New(Config{RedirectTrailingSlash: true})
+	server.Get("/health", func(ctx *fasthttp.RequestCtx) {})
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("http://example.com/health/?verbose=1")
+	ctx.Request.Header.SetMethod(MethodGet)
+
+	server.Handler()(ctx)
+
+	if code := ctx.Response.StatusCode(); code != fasthttp.StatusMovedPermanently {
+		t.Fatalf("expected status %d, got %d", fasthttp.StatusMovedPermanently, code)
+	}
+	if location := string(ctx.Response.Header.Peek("Location")); location != "http://example.com/health?verbose=1" {
+		t.Errorf("expected redirect to http://example.com/health?verbose=1, got %q", location)
+	}
+}
+
+func TestGroup(t *testing.T) {
+	var calls []string
+	handler := func(name string) Handler {
+		return func(ctx *fasthttp.RequestCtx) { calls = append(calls, name) }
+	}
+	record := func(name string) Middleware {
+		return Before(handler(name))
+	}
+
+	server := New()
+	api := server.Group("/api/", record("api"))
+	api.Route("/v1", func(v1 Router) {
+		v1.Get("/users/:id", handler("handler"), record("route"))
+		v1.Get("/", handler("index"))
+	}, record("v1"))
+
+	tests := []struct {
+		path          string
+		expectedCalls []string
+	}{
+		{"/api/v1/users/42", []string{"api", "v1", "route", "handler"}},
+		{"/api/v1", []string{"api", "v1", "index"}},
+	}
+
+	for _, test := range tests {
+		calls = nil
+
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI(test.path)
+		ctx.Request.Header.SetMethod(MethodGet)
+
+		server.Handler()(ctx)
+
+		if len(calls) != len(test.expectedCalls) {
+			t.Fatalf("%s: expected calls %v, got %v", test.path, test.expectedCalls, calls)
+		}
+		for i := range calls {
+			if calls[i] != test.expectedCalls[i] {
+				t.Errorf("%s: expected calls %v, got %v", test.path, test.expectedCalls, calls)
+				break
+			}
+		}
+	}
+}
+
+func TestMiddlewarePipeline(t *testing.T) {
+	var calls []string
+	wrap := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx *fasthttp.RequestCtx) {
+				calls = append(calls, name+":before")
+				next(ctx)
+				calls = append(calls, name+":after")
+			}
+		}
+	}
+
+	server := New()
+	server.Use(wrap("global"))
+	server.Post("/items", func(ctx *fasthttp.RequestCtx) {
+		calls = append(calls, "handler")
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+	}, Before(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusAccepted)
+	}), wrap("route"))
+	server.Post("/forbidden", func(ctx *fasthttp.RequestCtx) {
+		calls = append(calls, "handler")
+	}, Before(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusForbidden)
+		Abort(ctx)
+	}))
+
+	tests := []struct {
+		path          string
+		expectedCode  int
+		expectedCalls []string
+	}{
+		{"/items", fasthttp.StatusCreated, []string{"global:before", "route:before", "handler", "route:after", "global:after"}},
+		{"/forbidden", fasthttp.StatusForbidden, []string{"global:before", "global:after"}},
+		{"/missing", fasthttp.StatusNotFound, []string{"global:before", "global:after"}},
+	}
+
+	for _, test := range tests {
+		calls = nil
+
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI(test.path)
+		ctx.Request.Header.SetMethod(MethodPost)
+
+		server.Handler()(ctx)
+
+		if code := ctx.Response.StatusCode(); code != test.expectedCode {
+			t.Errorf("%s: expected status %d, got %d", test.path, test.expectedCode, code)
+		}
+		if len(calls) != len(test.expectedCalls) {
+			t.Fatalf("%s: expected calls %v, got %v", test.path, test.expectedCalls, calls)
+		}
+		for i := range calls {
+			if calls[i] != test.expectedCalls[i] {
+				t.Errorf("%s: expected calls %v, got %v", test.path, test.expectedCalls, calls)
+				break
+			}
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	server := New()
+	server.Get("/items", func(ctx *fasthttp.RequestCtx) { ctx.WriteString("items") })
+	server.Post("/items", func(ctx *fasthttp.RequestCtx) {})
+
+	tests := []struct {
+		method        string
+		expectedCode  int
+		expectedAllow string
+	}{
+		{MethodGet, fasthttp.StatusOK, ""},
+		{MethodHead, fasthttp.StatusOK, ""},
+		{MethodDelete, fasthttp.StatusMethodNotAllowed, "GET, HEAD, OPTIONS, POST"},
+		{MethodOptions, fasthttp.StatusNoContent, "GET, HEAD, OPTIONS, POST"},
+	}
+
+	for _, test := range tests {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI("/items")
+		ctx.Request.Header.SetMethod(test.method)
+
+		server.Handler()(ctx)
+
+		if code := ctx.Response.StatusCode(); code != test.expectedCode {
+			t.Errorf("%s: expected status %d, got %d", test.method, test.expectedCode, code)
+		}
+		if allow := string(ctx.Response.Header.Peek("Allow")); allow != test.expectedAllow {
+			t.Errorf("%s: expected Allow %q, got %q", test.method, test.expectedAllow, allow)
+		}
+	}
+}
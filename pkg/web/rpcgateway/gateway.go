@@ -0,0 +1,241 @@
+// Package rpcgateway exposes Nameko RPC methods as HTTP endpoints.
+package rpcgateway
+
+import (
+	"context"
+	"errors"
+	"github.com/joejoe-am/namego/pkg/rpc"
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Caller performs RPC calls against one service, *rpc.Client implements it.
+// Call must return once ctx is done.
+type Caller interface {
+	Call(ctx context.Context, methodName string, args interface{}, kwargs map[string]interface{}, contextData map[string]interface{}) (*rpc.Response, error)
+}
+
+// DefaultTimeout bounds how long an endpoint waits for the RPC reply.
+const DefaultTimeout = 30 * time.Second
+
+// DefaultErrorStatus maps remote exception types to HTTP status codes.
+// Exception types that are not listed are answered with 500.
+var DefaultErrorStatus = map[string]int{
+	"BadRequest":         fasthttp.StatusBadRequest,
+	"ValidationError":    fasthttp.StatusBadRequest,
+	"IncorrectSignature": fasthttp.StatusBadRequest,
+	"Unauthorized":       fasthttp.StatusUnauthorized,
+	"Unauthenticated":    fasthttp.StatusUnauthorized,
+	"Forbidden":          fasthttp.StatusForbidden,
+	"PermissionDenied":   fasthttp.StatusForbidden,
+	"NotFound":           fasthttp.StatusNotFound,
+	"MethodNotFound":     fasthttp.StatusNotFound,
+	"Conflict":           fasthttp.StatusConflict,
+	"UnknownService":     fasthttp.StatusServiceUnavailable,
+}
+
+// DefaultContextHeaders maps HTTP request headers to the Nameko context data
+// keys they are propagated as.
+var DefaultContextHeaders = map[string]string{
+	"Authorization":   "authorization",
+	"Accept-Language": "language",
+	"User-Agent":      "user_agent",
+	"X-Request-Id":    "request_id",
+}
+
+// Config configures a Gateway.
+type Config struct {
+	ErrorStatus    map[string]int              // Merged over DefaultErrorStatus
+	ContextHeaders map[string]string           // Replaces DefaultContextHeaders when set
+	NewCaller      func(service string) Caller // Defaults to rpc.NewClient
+	Timeout        time.Duration               // Endpoints without their own Timeout, DefaultTimeout if zero
+}
+
+// Endpoint declares an HTTP route served by an RPC method.
+type Endpoint struct {
+	HTTPMethod string // Defaults to POST
+	Path       string
+	Service    string
+	Method     string // RPC method name
+
+	// Args and Kwargs build the RPC arguments. If both are empty, the path
+	// parameters are passed as keyword arguments.
+	Args   []Source
+	Kwargs map[string]Source
+
+	// PassAll also passes every query argument and field of a JSON object
+	// body as keyword arguments when Args and Kwargs are empty. Clients can
+	// then set any argument of the RPC method, including ones the endpoint
+	// did not mean to expose, so only use it for methods safe to call that way.
+	PassAll bool
+
+	Status     int           // Success status, defaults to 200
+	Timeout    time.Duration // Answered with 504 when the reply takes longer, Config.Timeout if zero
+	Middleware []web.Middleware
+}
+
+// Gateway serves Endpoints by calling the RPC methods they declare.
+type Gateway struct {
+	errorStatus    map[string]int
+	contextHeaders map[string]string
+	newCaller      func(service string) Caller
+	timeout        time.Duration
+
+	mu      sync.Mutex
+	callers map[string]Caller
+}
+
+// New returns a Gateway. rpc.InitClient must have been called before
+// requests are served unless Config.NewCaller is set.
+func New(config ...Config) *Gateway {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	g := &Gateway{
+		errorStatus:    make(map[string]int),
+		contextHeaders: cfg.ContextHeaders,
+		newCaller:      cfg.NewCaller,
+		timeout:        cfg.Timeout,
+		callers:        make(map[string]Caller),
+	}
+
+	for excType, status := range DefaultErrorStatus {
+		g.errorStatus[excType] = status
+	}
+	for excType, status := range cfg.ErrorStatus {
+		g.errorStatus[excType] = status
+	}
+
+	if g.contextHeaders == nil {
+		g.contextHeaders = DefaultContextHeaders
+	}
+	if g.newCaller == nil {
+		g.newCaller = func(service string) Caller { return rpc.NewClient(service) }
+	}
+	if g.timeout <= 0 {
+		g.timeout = DefaultTimeout
+	}
+
+	return g
+}
+
+// Register adds a route to router for each endpoint.
+func (g *Gateway) Register(router web.Router, endpoints ...Endpoint) {
+	for _, endpoint := range endpoints {
+		method := endpoint.HTTPMethod
+		if method == "" {
+			method = web.MethodPost
+		}
+		router.Add([]string{method}, endpoint.Path, g.Handler(endpoint), endpoint.Middleware...)
+	}
+}
+
+// Handler returns a handler calling the endpoint's RPC method and writing
+// its result as JSON.
+func (g *Gateway) Handler(endpoint Endpoint) web.Handler {
+	caller := g.caller(endpoint.Service)
+	pathParams := pathParamNames(endpoint.Path)
+
+	status := endpoint.Status
+	if status == 0 {
+		status = fasthttp.StatusOK
+	}
+
+	timeout := endpoint.Timeout
+	if timeout <= 0 {
+		timeout = g.timeout
+	}
+
+	return func(ctx *fasthttp.RequestCtx) {
+		args, kwargs, err := buildArguments(ctx, endpoint, pathParams)
+		if err != nil {
+			web.JSONError(ctx, fasthttp.StatusBadRequest, err)
+			return
+		}
+
+		callCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		response, err := caller.Call(callCtx, endpoint.Method, args, kwargs, g.contextData(ctx))
+		if errors.Is(err, context.DeadlineExceeded) {
+			web.JSONError(ctx, fasthttp.StatusGatewayTimeout, &web.HTTPError{
+				Code:    fasthttp.StatusGatewayTimeout,
+				Message: fasthttp.StatusMessage(fasthttp.StatusGatewayTimeout),
+				Err:     err,
+			})
+			return
+		}
+		if err != nil {
+			web.JSONError(ctx, fasthttp.StatusBadGateway, g.httpError(err))
+			return
+		}
+
+		if err := web.JSON(ctx, status, response.Result); err != nil {
+			web.JSONError(ctx, fasthttp.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (g *Gateway) caller(service string) Caller {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	caller, ok := g.callers[service]
+	if !ok {
+		caller = g.newCaller(service)
+		g.callers[service] = caller
+	}
+	return caller
+}
+
+// contextData collects the configured request headers as Nameko context data.
+func (g *Gateway) contextData(ctx *fasthttp.RequestCtx) map[string]interface{} {
+	contextData := make(map[string]interface{})
+	for header, key := range g.contextHeaders {
+		if value := ctx.Request.Header.Peek(header); len(value) > 0 {
+			contextData[key] = string(value)
+		}
+	}
+	return contextData
+}
+
+// httpError maps remote exceptions to an HTTPError using the configured
+// statuses, falling back to the class name without its module path. Other
+// errors are returned unchanged and answered with 502.
+func (g *Gateway) httpError(err error) error {
+	var remoteErr *rpc.RemoteError
+	if !errors.As(err, &remoteErr) {
+		return err
+	}
+
+	excType := remoteErr.ExcType
+	status, ok := g.errorStatus[excType]
+	if !ok {
+		status, ok = g.errorStatus[excType[strings.LastIndex(excType, ".")+1:]]
+	}
+	if !ok {
+		return &web.HTTPError{
+			Code:    fasthttp.StatusInternalServerError,
+			Message: fasthttp.StatusMessage(fasthttp.StatusInternalServerError),
+			Err:     err,
+		}
+	}
+
+	return &web.HTTPError{Code: status, Message: remoteErr.Value, Err: err}
+}
+
+// pathParamNames returns the parameter and wildcard names of a route pattern.
+func pathParamNames(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			names = append(names, segment[1:])
+		}
+	}
+	return names
+}
@@ -0,0 +1,142 @@
+package rpcgateway
+
+import (
+	"context"
+	"github.com/joejoe-am/namego/pkg/rpc"
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type fakeCaller struct {
+	method      string
+	args        interface{}
+	kwargs      map[string]interface{}
+	contextData map[string]interface{}
+	response    *rpc.Response
+	err         error
+	hang        bool // Wait for ctx instead of replying
+}
+
+func (f *fakeCaller) Call(ctx context.Context, methodName string, args interface{}, kwargs map[string]interface{}, contextData map[string]interface{}) (*rpc.Response, error) {
+	f.method, f.args, f.kwargs, f.contextData = methodName, args, kwargs, contextData
+	if f.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return f.response, f.err
+}
+
+func TestGateway(t *testing.T) {
+	tests := []struct {
+		name           string
+		endpoint       Endpoint
+		uri            string
+		body           string
+		caller         *fakeCaller
+		expectedCode   int
+		expectedArgs   interface{}
+		expectedKwargs map[string]interface{}
+	}{
+		{
+			name:           "Default kwargs",
+			endpoint:       Endpoint{Path: "/orders/:id", Service: "orders", Method: "update"},
+			uri:            "/orders/7?notify=yes",
+			body:           `{"status": "paid"}`,
+			caller:         &fakeCaller{response: &rpc.Response{Result: "ok"}},
+			expectedCode:   fasthttp.StatusOK,
+			expectedArgs:   []interface{}{},
+			expectedKwargs: map[string]interface{}{"id": "7"},
+		},
+		{
+			name:           "Pass all kwargs",
+			endpoint:       Endpoint{Path: "/orders/:id", Service: "orders", Method: "update", PassAll: true},
+			uri:            "/orders/7?notify=yes&id=8",
+			body:           `{"status": "paid"}`,
+			caller:         &fakeCaller{response: &rpc.Response{Result: "ok"}},
+			expectedCode:   fasthttp.StatusOK,
+			expectedArgs:   []interface{}{},
+			expectedKwargs: map[string]interface{}{"id": "7", "notify": "yes", "status": "paid"},
+		},
+		{
+			name: "Explicit args",
+			endpoint: Endpoint{
+				Path:    "/orders/:id",
+				Service: "orders",
+				Method:  "update",
+				Args:    []Source{Path("id"), BodyField("status")},
+				Kwargs:  map[string]Source{"notify": Query("notify")},
+				Status:  fasthttp.StatusAccepted,
+			},
+			uri:            "/orders/7",
+			body:           `{"status": "paid"}`,
+			caller:         &fakeCaller{response: &rpc.Response{Result: "ok"}},
+			expectedCode:   fasthttp.StatusAccepted,
+			expectedArgs:   []interface{}{"7", "paid"},
+			expectedKwargs: map[string]interface{}{"notify": nil},
+		},
+		{
+			name:         "Mapped remote error",
+			endpoint:     Endpoint{Path: "/orders/:id", Service: "orders", Method: "update"},
+			uri:          "/orders/7",
+			caller:       &fakeCaller{err: &rpc.RemoteError{ExcType: "NotFound", Value: "no order 7"}},
+			expectedCode: fasthttp.StatusNotFound,
+		},
+		{
+			name:         "Unmapped remote error",
+			endpoint:     Endpoint{Path: "/orders/:id", Service: "orders", Method: "update"},
+			uri:          "/orders/7",
+			caller:       &fakeCaller{err: &rpc.RemoteError{ExcType: "KeyError", Value: "boom"}},
+			expectedCode: fasthttp.StatusInternalServerError,
+		},
+		{
+			name:         "Timeout",
+			endpoint:     Endpoint{Path: "/orders/:id", Service: "orders", Method: "update", Timeout: 10 * time.Millisecond},
+			uri:          "/orders/7",
+			caller:       &fakeCaller{hang: true},
+			expectedCode: fasthttp.StatusGatewayTimeout,
+		},
+		{
+			name:         "Invalid body",
+			endpoint:     Endpoint{Path: "/orders/:id", Service: "orders", Method: "update", PassAll: true},
+			uri:          "/orders/7",
+			body:         `[1, 2]`,
+			caller:       &fakeCaller{},
+			expectedCode: fasthttp.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gateway := New(Config{NewCaller: func(service string) Caller { return test.caller }})
+			server := web.New()
+			gateway.Register(server, test.endpoint)
+
+			ctx := &fasthttp.RequestCtx{}
+			ctx.Request.SetRequestURI(test.uri)
+			ctx.Request.Header.SetMethod(web.MethodPost)
+			ctx.Request.Header.Set("Authorization", "Bearer token")
+			ctx.Request.SetBodyString(test.body)
+
+			server.Handler()(ctx)
+
+			if code := ctx.Response.StatusCode(); code != test.expectedCode {
+				t.Errorf("expected status %d, got %d: %s", test.expectedCode, code, ctx.Response.Body())
+			}
+			if test.expectedKwargs == nil {
+				return
+			}
+			if !reflect.DeepEqual(test.caller.args, test.expectedArgs) {
+				t.Errorf("expected args %v, got %v", test.expectedArgs, test.caller.args)
+			}
+			if !reflect.DeepEqual(test.caller.kwargs, test.expectedKwargs) {
+				t.Errorf("expected kwargs %v, got %v", test.expectedKwargs, test.caller.kwargs)
+			}
+			if test.caller.contextData["authorization"] != "Bearer token" {
+				t.Errorf("expected authorization context data, got %v", test.caller.contextData)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package rpcgateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+)
+
+// Source extracts one RPC argument from the HTTP request.
+type Source func(ctx *fasthttp.RequestCtx) (interface{}, error)
+
+// Path passes the named path parameter as a string.
+func Path(name string) Source {
+	return func(ctx *fasthttp.RequestCtx) (interface{}, error) {
+		return web.Param(ctx, name), nil
+	}
+}
+
+// Query passes the named query argument as a string, or nil if it is absent.
+func Query(name string) Source {
+	return func(ctx *fasthttp.RequestCtx) (interface{}, error) {
+		value := ctx.QueryArgs().Peek(name)
+		if value == nil {
+			return nil, nil
+		}
+		return string(value), nil
+	}
+}
+
+// Header passes the named request header as a string, or nil if it is absent.
+func Header(name string) Source {
+	return func(ctx *fasthttp.RequestCtx) (interface{}, error) {
+		value := ctx.Request.Header.Peek(name)
+		if value == nil {
+			return nil, nil
+		}
+		return string(value), nil
+	}
+}
+
+// Body passes the decoded JSON body, or nil if the body is empty.
+func Body() Source {
+	return func(ctx *fasthttp.RequestCtx) (interface{}, error) {
+		var body interface{}
+		if err := web.BindJSON(ctx, &body); err != nil {
+			return nil, err
+		}
+		return body, nil
+	}
+}
+
+// BodyField passes a field of a JSON object body, or nil if it is absent.
+func BodyField(name string) Source {
+	return func(ctx *fasthttp.RequestCtx) (interface{}, error) {
+		body, err := bodyObject(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return body[name], nil
+	}
+}
+
+// Value passes a constant.
+func Value(v interface{}) Source {
+	return func(ctx *fasthttp.RequestCtx) (interface{}, error) {
+		return v, nil
+	}
+}
+
+// bodyObject decodes a JSON object body, an empty body yields an empty map.
+func bodyObject(ctx *fasthttp.RequestCtx) (map[string]interface{}, error) {
+	var body map[string]interface{}
+	if err := web.BindJSON(ctx, &body); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("request body must be a JSON object")
+		}
+		return nil, err
+	}
+	if body == nil {
+		body = map[string]interface{}{}
+	}
+	return body, nil
+}
+
+// buildArguments evaluates the endpoint's sources into RPC args and kwargs.
+func buildArguments(ctx *fasthttp.RequestCtx, endpoint Endpoint, pathParams []string) ([]interface{}, map[string]interface{}, error) {
+	args := make([]interface{}, 0, len(endpoint.Args))
+	kwargs := make(map[string]interface{})
+
+	if len(endpoint.Args) == 0 && len(endpoint.Kwargs) == 0 {
+		if endpoint.PassAll {
+			body, err := bodyObject(ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+			for key, value := range body {
+				kwargs[key] = value
+			}
+			ctx.QueryArgs().VisitAll(func(key, value []byte) {
+				kwargs[string(key)] = string(value)
+			})
+		}
+		for _, name := range pathParams {
+			kwargs[name] = web.Param(ctx, name)
+		}
+		return args, kwargs, nil
+	}
+
+	for _, source := range endpoint.Args {
+		value, err := source(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		args = append(args, value)
+	}
+
+	for key, source := range endpoint.Kwargs {
+		value, err := source(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		kwargs[key] = value
+	}
+
+	return args, kwargs, nil
+}
@@ -0,0 +1,110 @@
+package web
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"io"
+	"strings"
+	"time"
+)
+
+// DefaultSSEKeepAlive is how often a comment is sent on idle event streams so
+// that proxies keep the connection open and disconnects are noticed.
+const DefaultSSEKeepAlive = 15 * time.Second
+
+// ServerSentEvent is one event of a text/event-stream response.
+type ServerSentEvent struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration // Reconnection delay advised to the client, zero omits it
+}
+
+// WriteTo writes the event in the text/event-stream format.
+func (e ServerSentEvent) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", e.ID)
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", e.Event)
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", e.Retry.Milliseconds())
+	}
+	for _, line := range strings.Split(e.Data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// SSESubscription is the event source of one SSE client. Close is called
+// once the client disconnects.
+type SSESubscription struct {
+	Events <-chan ServerSentEvent
+	Close  func()
+}
+
+// SSE returns a handler streaming Server-Sent Events. subscribe is called for
+// each request, before the response is committed, so it can still reject the
+// request by returning an error (an HTTPError sets the status). The stream
+// ends when the client disconnects or the Events channel is closed.
+func SSE(subscribe func(ctx *fasthttp.RequestCtx) (SSESubscription, error), keepAlive ...time.Duration) Handler {
+	interval := DefaultSSEKeepAlive
+	if len(keepAlive) > 0 && keepAlive[0] > 0 {
+		interval = keepAlive[0]
+	}
+
+	return func(ctx *fasthttp.RequestCtx) {
+		sub, err := subscribe(ctx)
+		if err != nil {
+			JSONError(ctx, fasthttp.StatusInternalServerError, err)
+			return
+		}
+
+		ctx.SetContentType("text/event-stream")
+		ctx.Response.Header.Set("Cache-Control", "no-cache")
+		ctx.Response.Header.Set("Connection", "keep-alive")
+		ctx.Response.Header.Set("X-Accel-Buffering", "no")
+
+		// ctx must not be used inside the stream writer, which runs after the
+		// handler has returned.
+		ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+			if sub.Close != nil {
+				defer sub.Close()
+			}
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			// Commit the headers right away so clients see the stream open.
+			if _, err := w.WriteString(": connected\n\n"); err != nil || w.Flush() != nil {
+				return
+			}
+
+			for {
+				select {
+				case event, ok := <-sub.Events:
+					if !ok {
+						return
+					}
+					if _, err := event.WriteTo(w); err != nil {
+						return
+					}
+				case <-ticker.C:
+					if _, err := w.WriteString(": keep-alive\n\n"); err != nil {
+						return
+					}
+				}
+
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		})
+	}
+}
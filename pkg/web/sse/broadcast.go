@@ -0,0 +1,100 @@
+package sse
+
+import (
+	"context"
+	"github.com/joejoe-am/namego/pkg/rpc/events"
+	"github.com/joejoe-am/namego/pkg/web"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/valyala/fasthttp"
+	"log"
+	"strconv"
+	"time"
+)
+
+const stopTimeout = 5 * time.Second
+
+// startHandler runs an event handler, replaced in tests.
+var startHandler = func(ctx context.Context, handler *events.EventHandler, conn *amqp.Connection) error {
+	return handler.Start(ctx, conn)
+}
+
+// BroadcastHandler returns a handler giving every client its own broadcast
+// event queue, declared from cfg when the client connects and deleted when
+// it disconnects. HandlerType, ReliableDelivery and the handler functions of
+// cfg are overridden. Events published while a client is disconnected are
+// not replayed, use a Broker when reconnecting clients must not miss events.
+// The request is answered with an error if the queue cannot be declared or
+// consumed.
+func BroadcastHandler(conn *amqp.Connection, cfg events.EventConfig, keepAlive ...time.Duration) web.Handler {
+	if cfg.MethodName == "" {
+		cfg.MethodName = "sse"
+	}
+	cfg.HandlerType = events.Broadcast
+	cfg.ReliableDelivery = false
+	cfg.BroadcastID = ""
+	cfg.HandlerFunction = nil
+
+	return web.SSE(func(ctx *fasthttp.RequestCtx) (web.SSESubscription, error) {
+		types := requestedTypes(ctx)
+		stream := make(chan web.ServerSentEvent, 1)
+		done := make(chan struct{})
+
+		var nextID uint64
+		clientCfg := cfg
+		clientCfg.TypedHandlerFunction = func(eventType string, body []byte) error {
+			if types != nil && !types[eventType] {
+				return nil
+			}
+			nextID++
+			select {
+			case stream <- web.ServerSentEvent{ID: strconv.FormatUint(nextID, 10), Event: eventType, Data: string(body)}:
+			case <-done:
+			}
+			return nil
+		}
+		// Events are numbered per connection, so they must be handled in order.
+		clientCfg.Ordered = true
+
+		handler, err := events.NewEventHandler(clientCfg)
+		if err != nil {
+			return web.SSESubscription{}, err
+		}
+
+		runCtx, cancel := context.WithCancel(context.Background())
+		startErr := make(chan error, 1)
+		go func() {
+			err := startHandler(runCtx, handler, conn)
+			startErr <- err
+			// Start has drained its handlers, nothing sends on stream anymore.
+			close(stream)
+		}()
+
+		select {
+		case <-handler.Started():
+			go func() {
+				if err := <-startErr; err != nil {
+					log.Printf("SSE event handler error: %v", err)
+				}
+			}()
+		case err := <-startErr:
+			if err != nil {
+				cancel()
+				return web.SSESubscription{}, err
+			}
+		}
+
+		return web.SSESubscription{
+			Events: stream,
+			Close: func() {
+				close(done)
+				cancel()
+
+				stopCtx, stopCancel := context.WithTimeout(context.Background(), stopTimeout)
+				defer stopCancel()
+				if err := handler.Stop(stopCtx); err != nil {
+					log.Printf("failed to stop SSE event handler: %v", err)
+				}
+			},
+		}, nil
+	}, keepAlive...)
+}
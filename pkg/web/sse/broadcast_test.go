@@ -0,0 +1,35 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"github.com/joejoe-am/namego/pkg/rpc/events"
+	"github.com/joejoe-am/namego/pkg/web"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/valyala/fasthttp"
+	"testing"
+)
+
+func TestBroadcastHandlerStartError(t *testing.T) {
+	start := startHandler
+	startHandler = func(ctx context.Context, handler *events.EventHandler, conn *amqp.Connection) error {
+		return errors.New("queue declare failed")
+	}
+	defer func() { startHandler = start }()
+
+	handler := BroadcastHandler(nil, events.EventConfig{
+		ServiceName:   "web",
+		SourceService: "orders",
+		EventType:     "ORDER_CREATED",
+	})
+
+	ctx := subscribeCtx("/events", "")
+	handler(ctx)
+
+	if code := ctx.Response.StatusCode(); code != fasthttp.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", code)
+	}
+	if contentType := string(ctx.Response.Header.ContentType()); contentType != web.ContentTypeProblemJSON {
+		t.Errorf("expected a problem response, got %s", contentType)
+	}
+}
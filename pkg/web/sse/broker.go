@@ -0,0 +1,216 @@
+// Package sse streams Nameko events to HTTP clients as Server-Sent Events.
+//
+// A Broker fans events from a single events.EventHandler subscription out to
+// every connected client and keeps a short history so reconnecting clients
+// resume from their Last-Event-ID. BroadcastHandler instead gives each client
+// its own broadcast queue. Clients can narrow the stream to some event types
+// with ?types=ORDER_CREATED,ORDER_PAID.
+package sse
+
+import (
+	"github.com/joejoe-am/namego/pkg/rpc/events"
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LastEventIDHeader is sent by EventSource clients when they reconnect.
+const LastEventIDHeader = "Last-Event-ID"
+
+// BrokerConfig configures a Broker.
+type BrokerConfig struct {
+	History      int           // Events kept for Last-Event-ID replay, defaults to 256
+	ClientBuffer int           // Events buffered per client before it is dropped, defaults to 64
+	KeepAlive    time.Duration // Defaults to web.DefaultSSEKeepAlive
+}
+
+// Broker fans published events out to SSE clients.
+type Broker struct {
+	config BrokerConfig
+
+	mu      sync.Mutex
+	nextID  uint64
+	history []web.ServerSentEvent
+	clients map[*client]struct{}
+	closed  bool
+}
+
+type client struct {
+	events chan web.ServerSentEvent
+	types  map[string]bool // nil accepts every event type
+}
+
+// NewBroker returns a Broker. Feed it with EventHandler or Publish and serve
+// it with Handler.
+func NewBroker(config ...BrokerConfig) *Broker {
+	var cfg BrokerConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.History <= 0 {
+		cfg.History = 256
+	}
+	if cfg.ClientBuffer <= 0 {
+		cfg.ClientBuffer = 64
+	}
+
+	return &Broker{
+		config:  cfg,
+		clients: make(map[*client]struct{}),
+	}
+}
+
+// EventHandler returns an event handler function publishing every event it
+// receives. Use it as EventConfig.TypedHandlerFunction.
+func (b *Broker) EventHandler() events.TypedEventHandlerType {
+	return func(eventType string, body []byte) error {
+		b.Publish(eventType, body)
+		return nil
+	}
+}
+
+// Publish sends an event to every client subscribed to its type. Clients
+// whose buffer is full are disconnected, they resume from history when they
+// reconnect.
+func (b *Broker) Publish(eventType string, data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.nextID++
+	event := web.ServerSentEvent{
+		ID:    strconv.FormatUint(b.nextID, 10),
+		Event: eventType,
+		Data:  string(data),
+	}
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.config.History {
+		b.history = b.history[len(b.history)-b.config.History:]
+	}
+
+	for c := range b.clients {
+		if !c.accepts(eventType) {
+			continue
+		}
+		select {
+		case c.events <- event:
+		default:
+			log.Printf("SSE client is too slow, disconnecting")
+			b.remove(c)
+		}
+	}
+}
+
+// Handler returns the handler streaming the broker's events.
+func (b *Broker) Handler() web.Handler {
+	return web.SSE(b.subscribe, b.config.KeepAlive)
+}
+
+// Close disconnects every client and stops accepting new ones.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for c := range b.clients {
+		b.remove(c)
+	}
+}
+
+func (b *Broker) subscribe(ctx *fasthttp.RequestCtx) (web.SSESubscription, error) {
+	types := requestedTypes(ctx)
+	lastID, resume := lastEventID(ctx)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return web.SSESubscription{}, web.NewHTTPError(fasthttp.StatusServiceUnavailable, "event stream is closed")
+	}
+
+	var replay []web.ServerSentEvent
+	if resume {
+		for _, event := range b.history {
+			id, _ := strconv.ParseUint(event.ID, 10, 64)
+			if id > lastID && (types == nil || types[event.Event]) {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	c := &client{
+		events: make(chan web.ServerSentEvent, b.config.ClientBuffer+len(replay)),
+		types:  types,
+	}
+	for _, event := range replay {
+		c.events <- event
+	}
+	b.clients[c] = struct{}{}
+
+	return web.SSESubscription{
+		Events: c.events,
+		Close: func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			b.remove(c)
+		},
+	}, nil
+}
+
+// remove unregisters c and closes its channel, which ends its stream.
+func (b *Broker) remove(c *client) {
+	if _, ok := b.clients[c]; !ok {
+		return
+	}
+	delete(b.clients, c)
+	close(c.events)
+}
+
+func (c *client) accepts(eventType string) bool {
+	return c.types == nil || c.types[eventType]
+}
+
+// requestedTypes reads the event type filter from the types query argument,
+// given as a comma separated list or repeated. It returns nil for no filter.
+func requestedTypes(ctx *fasthttp.RequestCtx) map[string]bool {
+	var types map[string]bool
+	for _, value := range ctx.QueryArgs().PeekMulti("types") {
+		for _, eventType := range strings.Split(string(value), ",") {
+			if eventType = strings.TrimSpace(eventType); eventType == "" {
+				continue
+			}
+			if types == nil {
+				types = make(map[string]bool)
+			}
+			types[eventType] = true
+		}
+	}
+	return types
+}
+
+// lastEventID reads the ID of the last event a reconnecting client received,
+// from the Last-Event-ID header or, for clients that cannot set headers, the
+// last_event_id query argument.
+func lastEventID(ctx *fasthttp.RequestCtx) (uint64, bool) {
+	value := ctx.Request.Header.Peek(LastEventIDHeader)
+	if len(value) == 0 {
+		value = ctx.QueryArgs().Peek("last_event_id")
+	}
+	if len(value) == 0 {
+		return 0, false
+	}
+
+	id, err := strconv.ParseUint(string(value), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,67 @@
+package sse
+
+import (
+	"bytes"
+	"github.com/valyala/fasthttp"
+	"testing"
+)
+
+func subscribeCtx(uri, lastEventID string) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI(uri)
+	if lastEventID != "" {
+		ctx.Request.Header.Set(LastEventIDHeader, lastEventID)
+	}
+	return ctx
+}
+
+func TestBroker(t *testing.T) {
+	broker := NewBroker(BrokerConfig{History: 2})
+
+	live, err := broker.subscribe(subscribeCtx("/events?types=ORDER_PAID", ""))
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	broker.Publish("ORDER_CREATED", []byte(`{"id": 1}`))
+	broker.Publish("ORDER_PAID", []byte(`{"id": 1}`))
+	broker.Publish("ORDER_CREATED", []byte(`{"id": 2}`))
+
+	if event := <-live.Events; event.ID != "2" || event.Event != "ORDER_PAID" {
+		t.Errorf("expected filtered ORDER_PAID event 2, got %+v", event)
+	}
+	if len(live.Events) != 0 {
+		t.Errorf("expected other event types to be filtered")
+	}
+
+	resumed, err := broker.subscribe(subscribeCtx("/events", "1"))
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	for _, expectedID := range []string{"2", "3"} {
+		if event := <-resumed.Events; event.ID != expectedID {
+			t.Errorf("expected replayed event %s, got %+v", expectedID, event)
+		}
+	}
+
+	live.Close()
+	broker.Close()
+	if _, ok := <-resumed.Events; ok {
+		t.Errorf("expected stream to end when the broker is closed")
+	}
+}
+
+func TestServerSentEventFormat(t *testing.T) {
+	var buf bytes.Buffer
+	broker := NewBroker()
+	broker.Publish("ORDER_CREATED", []byte("line one\nline two"))
+
+	if _, err := broker.history[0].WriteTo(&buf); err != nil {
+		t.Fatalf("failed to write event: %v", err)
+	}
+
+	expected := "id: 1\nevent: ORDER_CREATED\ndata: line one\ndata: line two\n\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
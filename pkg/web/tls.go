@@ -0,0 +1,91 @@
+package web
+
+import (
+	"crypto/tls"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// certificateReloader serves a TLS certificate that can be reloaded from disk
+// without restarting the server, e.g. after cert-manager rotated it.
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.RWMutex
+	certificate *tls.Certificate
+	modTime     time.Time
+}
+
+func newCertificateReloader(certFile, keyFile string) (*certificateReloader, error) {
+	reloader := &certificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if err := reloader.reload(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+// reload reads the certificate and key files again. Their modification time
+// is taken before loading, so files rewritten meanwhile are reloaded by watch.
+func (r *certificateReloader) reload() error {
+	modTime := r.latestModTime()
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.certificate = &certificate
+	r.modTime = modTime
+	r.mu.Unlock()
+
+	return nil
+}
+
+// getCertificate implements tls.Config.GetCertificate.
+func (r *certificateReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.certificate, nil
+}
+
+// watch reloads the certificate whenever one of the files changes, until stop is closed.
+func (r *certificateReloader) watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.mu.RLock()
+			changed := r.latestModTime().After(r.modTime)
+			r.mu.RUnlock()
+
+			if !changed {
+				continue
+			}
+			if err := r.reload(); err != nil {
+				log.Printf("failed to reload TLS certificate: %v", err)
+			}
+		}
+	}
+}
+
+func (r *certificateReloader) latestModTime() time.Time {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		if info, err := os.Stat(file); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
@@ -0,0 +1,170 @@
+package web
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// node is a segment of the routing trie. Children are tried in priority
+// order: static segments, then a named parameter (":id"), then a catch-all
+// wildcard ("*path") which must be the last segment of a pattern.
+type node struct {
+	static       map[string]*node
+	param        *node
+	paramName    string
+	wildcard     *node
+	wildcardName string
+	pattern      string            // Registered path pattern, set on nodes that hold routes
+	routes       map[string]*Route // Routes by HTTP method
+}
+
+// param is a path parameter captured while matching a request path.
+type param struct {
+	key   string
+	value string
+}
+
+func newNode() *node {
+	return &node{
+		static: make(map[string]*node),
+		routes: make(map[string]*Route),
+	}
+}
+
+// splitPath splits a path into segments, "/users/42" becomes ["users", "42"]
+// and "/" becomes [""] so that a trailing slash is a distinct segment.
+func splitPath(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
+
+// insert registers route for its methods under the given pattern.
+func (n *node) insert(pattern string, route *Route) {
+	if !strings.HasPrefix(pattern, "/") {
+		panic(fmt.Sprintf("web: path %q must begin with '/'", pattern))
+	}
+
+	segments := splitPath(pattern)
+	current := n
+
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			name := segment[1:]
+			if name == "" {
+				panic(fmt.Sprintf("web: empty parameter name in path %q", pattern))
+			}
+			if current.param == nil {
+				current.param = newNode()
+				current.paramName = name
+			} else if current.paramName != name {
+				panic(fmt.Sprintf("web: parameter %q in path %q conflicts with existing parameter %q", name, pattern, current.paramName))
+			}
+			current = current.param
+
+		case strings.HasPrefix(segment, "*"):
+			name := segment[1:]
+			if name == "" {
+				panic(fmt.Sprintf("web: empty wildcard name in path %q", pattern))
+			}
+			if i != len(segments)-1 {
+				panic(fmt.Sprintf("web: wildcard must be the last segment in path %q", pattern))
+			}
+			if current.wildcard == nil {
+				current.wildcard = newNode()
+				current.wildcardName = name
+			} else if current.wildcardName != name {
+				panic(fmt.Sprintf("web: wildcard %q in path %q conflicts with existing wildcard %q", name, pattern, current.wildcardName))
+			}
+			current = current.wildcard
+
+		default:
+			child, ok := current.static[segment]
+			if !ok {
+				child = newNode()
+				current.static[segment] = child
+			}
+			current = child
+		}
+	}
+
+	for _, method := range route.Methods {
+		if _, exists := current.routes[method]; exists {
+			panic(fmt.Sprintf("web: route %s %s is already registered", method, pattern))
+		}
+		current.routes[method] = route
+	}
+	current.pattern = pattern
+}
+
+// lookup returns the node holding routes for path along with the captured
+// path parameters, or nil if no registered pattern matches.
+func (n *node) lookup(path string) (*node, []param) {
+	var params []param
+	found := n.match(splitPath(path), &params)
+	if found == nil {
+		return nil, nil
+	}
+	return found, params
+}
+
+func (n *node) match(segments []string, params *[]param) *node {
+	if len(segments) == 0 {
+		if len(n.routes) > 0 {
+			return n
+		}
+		return nil
+	}
+
+	segment := segments[0]
+
+	if child, ok := n.static[segment]; ok {
+		if found := child.match(segments[1:], params); found != nil {
+			return found
+		}
+	}
+
+	if n.param != nil && segment != "" {
+		mark := len(*params)
+		*params = append(*params, param{key: n.paramName, value: segment})
+		if found := n.param.match(segments[1:], params); found != nil {
+			return found
+		}
+		*params = (*params)[:mark]
+	}
+
+	if n.wildcard != nil && len(n.wildcard.routes) > 0 {
+		*params = append(*params, param{key: n.wildcardName, value: strings.Join(segments, "/")})
+		return n.wildcard
+	}
+
+	return nil
+}
+
+// allows reports whether the node serves method, including the implicit HEAD
+// for GET routes and OPTIONS.
+func (n *node) allows(method string) bool {
+	if _, ok := n.routes[method]; ok {
+		return true
+	}
+	if method == MethodHead {
+		_, ok := n.routes[MethodGet]
+		return ok
+	}
+	return method == MethodOptions
+}
+
+// allow returns the value of the Allow header for the node.
+func (n *node) allow() string {
+	methods := make([]string, 0, len(n.routes)+2)
+	for method := range n.routes {
+		methods = append(methods, method)
+	}
+	for _, method := range []string{MethodHead, MethodOptions} {
+		if _, ok := n.routes[method]; !ok && n.allows(method) {
+			methods = append(methods, method)
+		}
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
@@ -0,0 +1,45 @@
+// Package webmetrics serves the namego metrics over HTTP and records the HTTP
+// metrics of a web.Server. It is kept apart from pkg/metrics so RPC-only
+// binaries do not link the web stack.
+package webmetrics
+
+import (
+	"github.com/joejoe-am/namego/pkg/metrics"
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+	"strconv"
+	"time"
+)
+
+// unmatchedRoute labels requests that matched no route, so that scanners
+// probing random paths do not create unbounded label values.
+const unmatchedRoute = "unmatched"
+
+// Handler serves metrics.Registry in the Prometheus text format.
+func Handler() web.Handler {
+	handler := fasthttpadaptor.NewFastHTTPHandler(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}))
+	return web.Handler(handler)
+}
+
+// Middleware records HTTP request counts and latency by route pattern.
+// Register it with Server.Use so unmatched requests are counted as well.
+func Middleware() web.Middleware {
+	return func(next web.Handler) web.Handler {
+		return func(ctx *fasthttp.RequestCtx) {
+			start := time.Now()
+
+			next(ctx)
+
+			route := web.RoutePattern(ctx)
+			if route == "" {
+				route = unmatchedRoute
+			}
+			method := string(ctx.Method())
+
+			metrics.HTTPRequests.WithLabelValues(method, route, strconv.Itoa(ctx.Response.StatusCode())).Inc()
+			metrics.HTTPDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package webmetrics
+
+import (
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+	"net"
+	"strings"
+	"testing"
+)
+
+func serve(server *web.Server, method, uri string) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.SetRequestURI(uri)
+	req.Header.SetMethod(method)
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(&req, &net.TCPAddr{IP: net.ParseIP("10.0.0.1")}, nil)
+
+	server.Handler()(ctx)
+	return ctx
+}
+
+func TestMiddlewareLabelsByRoutePattern(t *testing.T) {
+	server := web.New()
+	server.Use(Middleware())
+	server.Get("/users/:id", func(ctx *fasthttp.RequestCtx) { ctx.WriteString("user") })
+	server.Get("/metrics", Handler())
+
+	serve(server, web.MethodGet, "/users/42")
+	serve(server, web.MethodGet, "/users/43")
+	serve(server, web.MethodGet, "/missing/path")
+
+	body := string(serve(server, web.MethodGet, "/metrics").Response.Body())
+
+	tests := []string{
+		`namego_http_requests_total{method="GET",route="/users/:id",status="200"} 2`,
+		`namego_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`namego_http_request_duration_seconds_count{method="GET",route="/users/:id"} 2`,
+		"go_goroutines",
+	}
+	for _, want := range tests {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics output to contain %q", want)
+		}
+	}
+}
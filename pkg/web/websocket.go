@@ -0,0 +1,47 @@
+package web
+
+import (
+	"github.com/fasthttp/websocket"
+	"github.com/valyala/fasthttp"
+	"time"
+)
+
+// WebSocketConfig configures the WebSocket upgrade.
+type WebSocketConfig struct {
+	HandshakeTimeout  time.Duration
+	ReadBufferSize    int
+	WriteBufferSize   int
+	Subprotocols      []string
+	EnableCompression bool
+
+	// CheckOrigin returns true if the request Origin is acceptable. By default
+	// cross-origin requests are rejected.
+	CheckOrigin func(ctx *fasthttp.RequestCtx) bool
+}
+
+// WebSocket returns a handler upgrading the request to a WebSocket connection
+// and passing it to handler, which owns the connection until it returns.
+// Requests that are not WebSocket upgrades are answered with 400. Register it
+// as a GET route:
+//
+//	server.Get("/ws", web.WebSocket(func(conn *websocket.Conn) { ... }))
+func WebSocket(handler func(conn *websocket.Conn), config ...WebSocketConfig) Handler {
+	var cfg WebSocketConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	upgrader := websocket.FastHTTPUpgrader{
+		HandshakeTimeout:  cfg.HandshakeTimeout,
+		ReadBufferSize:    cfg.ReadBufferSize,
+		WriteBufferSize:   cfg.WriteBufferSize,
+		Subprotocols:      cfg.Subprotocols,
+		EnableCompression: cfg.EnableCompression,
+		CheckOrigin:       cfg.CheckOrigin,
+	}
+
+	return func(ctx *fasthttp.RequestCtx) {
+		// The upgrader writes the error response itself.
+		_ = upgrader.Upgrade(ctx, handler)
+	}
+}
@@ -0,0 +1,254 @@
+// Package webtest runs a web.Server over an in-memory listener, so full
+// request handling, including middleware and error responses, can be tested
+// without binding a port.
+package webtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// DefaultTimeout bounds every request made through the harness.
+const DefaultTimeout = 5 * time.Second
+
+// Server serves a web.Server over a fasthttputil.InmemoryListener. Each
+// Server has its own listener, so tests using separate Servers can run in
+// parallel.
+type Server struct {
+	t        testing.TB
+	server   *web.Server
+	listener *fasthttputil.InmemoryListener
+	client   *fasthttp.Client
+	done     chan struct{}
+}
+
+// New starts serving server and shuts it down when the test finishes.
+func New(t testing.TB, server *web.Server) *Server {
+	t.Helper()
+
+	s := &Server{
+		t:        t,
+		server:   server,
+		listener: fasthttputil.NewInmemoryListener(),
+		done:     make(chan struct{}),
+	}
+	s.client = &fasthttp.Client{
+		Dial: func(addr string) (net.Conn, error) {
+			return s.listener.Dial()
+		},
+	}
+
+	go func() {
+		defer close(s.done)
+		if err := server.Serve(s.listener); err != nil {
+			t.Errorf("webtest: serve: %v", err)
+		}
+	}()
+
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// WithT returns a Server sending requests on behalf of t, for subtests
+// sharing a parent test's server. Failures are then reported on t.
+func (s *Server) WithT(t testing.TB) *Server {
+	c := *s
+	c.t = t
+	return &c
+}
+
+// Close shuts the server down. It is called automatically at the end of the
+// test.
+func (s *Server) Close() {
+	s.client.CloseIdleConnections()
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.t.Errorf("webtest: shutdown: %v", err)
+	}
+	<-s.done
+}
+
+// NewRequest starts building a request with the given method and path. The
+// path may include a query string.
+func (s *Server) NewRequest(method, path string) *Request {
+	req := &fasthttp.Request{}
+	req.Header.SetMethod(method)
+	req.SetRequestURI("http://webtest" + path)
+
+	return &Request{t: s.t, server: s, req: req}
+}
+
+func (s *Server) Get(path string) *Request     { return s.NewRequest(web.MethodGet, path) }
+func (s *Server) Head(path string) *Request    { return s.NewRequest(web.MethodHead, path) }
+func (s *Server) Post(path string) *Request    { return s.NewRequest(web.MethodPost, path) }
+func (s *Server) Put(path string) *Request     { return s.NewRequest(web.MethodPut, path) }
+func (s *Server) Patch(path string) *Request   { return s.NewRequest(web.MethodPatch, path) }
+func (s *Server) Delete(path string) *Request  { return s.NewRequest(web.MethodDelete, path) }
+func (s *Server) Options(path string) *Request { return s.NewRequest(web.MethodOptions, path) }
+
+// Request is a request under construction. Its methods return the Request so
+// calls can be chained, ending with Do.
+type Request struct {
+	t      testing.TB
+	server *Server
+	req    *fasthttp.Request
+}
+
+// Header sets a request header.
+func (r *Request) Header(name, value string) *Request {
+	r.req.Header.Set(name, value)
+	return r
+}
+
+// Query adds a query string argument.
+func (r *Request) Query(name, value string) *Request {
+	r.req.URI().QueryArgs().Add(name, value)
+	return r
+}
+
+// Cookie sets a request cookie.
+func (r *Request) Cookie(name, value string) *Request {
+	r.req.Header.SetCookie(name, value)
+	return r
+}
+
+// Body sets the request body and its content type.
+func (r *Request) Body(contentType string, body []byte) *Request {
+	r.req.Header.SetContentType(contentType)
+	r.req.SetBody(body)
+	return r
+}
+
+// JSON sets v, encoded as JSON, as the request body.
+func (r *Request) JSON(v interface{}) *Request {
+	r.t.Helper()
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		r.t.Fatalf("webtest: encoding request body: %v", err)
+	}
+	return r.Body(web.ContentTypeJSON, body)
+}
+
+// Do sends the request and returns the response. Transport errors fail the
+// test immediately.
+func (r *Request) Do() *Response {
+	r.t.Helper()
+
+	resp := &fasthttp.Response{}
+	if err := r.server.client.DoTimeout(r.req, resp, DefaultTimeout); err != nil {
+		if errors.Is(err, fasthttp.ErrTimeout) {
+			r.t.Fatalf("webtest: %s %s timed out", r.req.Header.Method(), r.req.URI().RequestURI())
+		}
+		r.t.Fatalf("webtest: %s %s: %v", r.req.Header.Method(), r.req.URI().RequestURI(), err)
+	}
+
+	return &Response{t: r.t, resp: resp}
+}
+
+// Response is a received response. Its Expect methods report mismatches with
+// t.Errorf and return the Response so assertions can be chained.
+type Response struct {
+	t    testing.TB
+	resp *fasthttp.Response
+}
+
+// StatusCode returns the response status code.
+func (r *Response) StatusCode() int {
+	return r.resp.StatusCode()
+}
+
+// Header returns the value of a response header.
+func (r *Response) Header(name string) string {
+	return string(r.resp.Header.Peek(name))
+}
+
+// Body returns the response body.
+func (r *Response) Body() []byte {
+	return r.resp.Body()
+}
+
+// DecodeJSON decodes the response body into v, failing the test if it is not
+// valid JSON.
+func (r *Response) DecodeJSON(v interface{}) {
+	r.t.Helper()
+
+	if err := json.Unmarshal(r.resp.Body(), v); err != nil {
+		r.t.Fatalf("webtest: decoding response body %q: %v", r.resp.Body(), err)
+	}
+}
+
+// ExpectStatus checks the status code.
+func (r *Response) ExpectStatus(code int) *Response {
+	r.t.Helper()
+
+	if got := r.resp.StatusCode(); got != code {
+		r.t.Errorf("expected status %d, got %d (body %q)", code, got, r.resp.Body())
+	}
+	return r
+}
+
+// ExpectHeader checks the value of a response header.
+func (r *Response) ExpectHeader(name, value string) *Response {
+	r.t.Helper()
+
+	if got := r.Header(name); got != value {
+		r.t.Errorf("expected header %s %q, got %q", name, value, got)
+	}
+	return r
+}
+
+// ExpectBody checks the response body.
+func (r *Response) ExpectBody(body string) *Response {
+	r.t.Helper()
+
+	if got := string(r.resp.Body()); got != body {
+		r.t.Errorf("expected body %q, got %q", body, got)
+	}
+	return r
+}
+
+// ExpectBodyContains checks that the response body contains substr.
+func (r *Response) ExpectBodyContains(substr string) *Response {
+	r.t.Helper()
+
+	if !bytes.Contains(r.resp.Body(), []byte(substr)) {
+		r.t.Errorf("expected body to contain %q, got %q", substr, r.resp.Body())
+	}
+	return r
+}
+
+// ExpectJSON checks that the response body is JSON equal to v once both are
+// decoded, so key order and formatting do not matter.
+func (r *Response) ExpectJSON(v interface{}) *Response {
+	r.t.Helper()
+
+	expected, err := json.Marshal(v)
+	if err != nil {
+		r.t.Fatalf("webtest: encoding expected body: %v", err)
+	}
+
+	var want, got interface{}
+	_ = json.Unmarshal(expected, &want)
+	if err := json.Unmarshal(r.resp.Body(), &got); err != nil {
+		r.t.Errorf("expected JSON body %s, got %q: %v", expected, r.resp.Body(), err)
+		return r
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		r.t.Errorf("expected JSON body %s, got %s", expected, r.resp.Body())
+	}
+	return r
+}
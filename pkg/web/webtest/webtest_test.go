@@ -0,0 +1,83 @@
+package webtest
+
+import (
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+	"testing"
+)
+
+type greeting struct {
+	Name    string `json:"name" path:"name"`
+	Excited bool   `json:"excited" query:"excited"`
+}
+
+func newServer() *web.Server {
+	server := web.New()
+	server.Use(func(next web.Handler) web.Handler {
+		return func(ctx *fasthttp.RequestCtx) {
+			ctx.Response.Header.Set("X-Order", "global")
+			next(ctx)
+		}
+	})
+
+	server.Get("/hello/:name", web.Typed(func(ctx *fasthttp.RequestCtx, req greeting) (map[string]string, error) {
+		message := "hello " + req.Name
+		if req.Excited {
+			message += "!"
+		}
+		return map[string]string{"message": message}, nil
+	}), func(next web.Handler) web.Handler {
+		return func(ctx *fasthttp.RequestCtx) {
+			next(ctx)
+			ctx.Response.Header.Set("X-Order", string(ctx.Response.Header.Peek("X-Order"))+",route")
+		}
+	})
+
+	server.Post("/echo", func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType(string(ctx.Request.Header.ContentType()))
+		ctx.SetBody(ctx.PostBody())
+	})
+
+	return server
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+	s := New(t, newServer())
+
+	s.Get("/hello/ada").Query("excited", "true").Do().
+		ExpectStatus(fasthttp.StatusOK).
+		ExpectHeader("X-Order", "global,route").
+		ExpectJSON(map[string]string{"message": "hello ada!"})
+
+	s.Post("/echo").JSON(greeting{Name: "bob"}).Do().
+		ExpectStatus(fasthttp.StatusOK).
+		ExpectHeader("Content-Type", web.ContentTypeJSON).
+		ExpectJSON(greeting{Name: "bob"})
+
+	s.Get("/missing").Do().ExpectStatus(fasthttp.StatusNotFound)
+
+	resp := s.Put("/echo").Do().ExpectStatus(fasthttp.StatusMethodNotAllowed)
+	if allow := resp.Header("Allow"); allow != "OPTIONS, POST" {
+		t.Errorf("expected Allow \"OPTIONS, POST\", got %q", allow)
+	}
+}
+
+func TestServerParallel(t *testing.T) {
+	t.Parallel()
+	s := New(t, newServer())
+
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			s := s.WithT(t)
+
+			var body map[string]string
+			s.Get("/hello/" + name).Do().ExpectStatus(fasthttp.StatusOK).DecodeJSON(&body)
+			if body["message"] != "hello "+name {
+				t.Errorf("expected greeting for %s, got %q", name, body["message"])
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+// Package wshub implements a WebSocket hub in the style of nameko.web: clients
+// call registered methods over a JSON protocol and subscribe to channels that
+// server code, or Nameko events, broadcast into.
+//
+// Clients send {"method": "...", "data": {...}, "correlation_id": "..."} and
+// receive {"type": "result", "correlation_id": "...", "success": true, "data": ...}
+// or {"type": "result", "success": false, "error": {"type": "...", "message": "..."}}.
+// Broadcasts arrive as {"type": "event", "event": "...", "data": ...}.
+package wshub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fasthttp/websocket"
+	"github.com/google/uuid"
+	"github.com/joejoe-am/namego/pkg/rpc"
+	"github.com/joejoe-am/namego/pkg/rpc/events"
+	"github.com/joejoe-am/namego/pkg/web"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	sendBufferSize = 64
+	writeTimeout   = 10 * time.Second
+	pongTimeout    = 60 * time.Second
+	pingInterval   = pongTimeout * 9 / 10
+
+	// maxInFlightCalls bounds the method calls one socket may have running.
+	maxInFlightCalls = 16
+)
+
+// ErrMethodNotFound is returned to clients calling an unregistered method.
+var ErrMethodNotFound = errors.New("method not found")
+
+// ErrTooManyCalls is returned to clients that send a call while too many of
+// their previous calls are still running.
+var ErrTooManyCalls = errors.New("too many calls in flight")
+
+// Method handles a client call. The returned value is sent back as JSON.
+type Method func(socketID string, data json.RawMessage) (interface{}, error)
+
+// Hub tracks connected sockets and their channel subscriptions.
+type Hub struct {
+	mu       sync.RWMutex
+	methods  map[string]Method
+	sockets  map[string]*socket
+	channels map[string]map[string]*socket // channel -> socket ID -> socket
+	maxCalls int                           // In-flight calls allowed per socket
+}
+
+// NewHub returns a Hub with the built-in subscribe and unsubscribe methods,
+// which take {"channel": "..."} as data.
+func NewHub() *Hub {
+	h := &Hub{
+		methods:  make(map[string]Method),
+		sockets:  make(map[string]*socket),
+		channels: make(map[string]map[string]*socket),
+		maxCalls: maxInFlightCalls,
+	}
+
+	h.RegisterMethod(MethodSubscribe, func(socketID string, data json.RawMessage) (interface{}, error) {
+		channel, err := decodeChannel(data)
+		if err != nil {
+			return nil, err
+		}
+		return nil, h.Subscribe(socketID, channel)
+	})
+	h.RegisterMethod(MethodUnsubscribe, func(socketID string, data json.RawMessage) (interface{}, error) {
+		channel, err := decodeChannel(data)
+		if err != nil {
+			return nil, err
+		}
+		h.Unsubscribe(socketID, channel)
+		return nil, nil
+	})
+
+	return h
+}
+
+// RegisterMethod registers a method clients can call.
+func (h *Hub) RegisterMethod(name string, method Method) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.methods[name] = method
+}
+
+// Handler returns the handler upgrading requests to hub connections.
+func (h *Hub) Handler(config ...web.WebSocketConfig) web.Handler {
+	return web.WebSocket(h.serve, config...)
+}
+
+// Subscribe adds the socket to channel.
+func (h *Hub) Subscribe(socketID, channel string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.sockets[socketID]
+	if !ok {
+		return fmt.Errorf("unknown socket %s", socketID)
+	}
+
+	subscribers, ok := h.channels[channel]
+	if !ok {
+		subscribers = make(map[string]*socket)
+		h.channels[channel] = subscribers
+	}
+	subscribers[socketID] = s
+	s.channels[channel] = true
+
+	return nil
+}
+
+// Unsubscribe removes the socket from channel.
+func (h *Hub) Unsubscribe(socketID, channel string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.unsubscribe(socketID, channel)
+}
+
+func (h *Hub) unsubscribe(socketID, channel string) {
+	if s, ok := h.sockets[socketID]; ok {
+		delete(s.channels, channel)
+	}
+
+	subscribers := h.channels[channel]
+	delete(subscribers, socketID)
+	if len(subscribers) == 0 {
+		delete(h.channels, channel)
+	}
+}
+
+// Broadcast pushes an event to every socket subscribed to channel.
+func (h *Hub) Broadcast(channel, event string, data interface{}) error {
+	payload, err := json.Marshal(eventMessage(event, data))
+	if err != nil {
+		return err
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, s := range h.channels[channel] {
+		s.send(payload)
+	}
+
+	return nil
+}
+
+// Unicast pushes an event to one socket, returning false if it is not connected.
+func (h *Hub) Unicast(socketID, event string, data interface{}) (bool, error) {
+	payload, err := json.Marshal(eventMessage(event, data))
+	if err != nil {
+		return false, err
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	s, ok := h.sockets[socketID]
+	if ok {
+		s.send(payload)
+	}
+
+	return ok, nil
+}
+
+// EventForwarder returns an event handler function broadcasting every Nameko
+// event it receives into channel, with the event type as event name and the
+// event payload as data. Use it as EventConfig.TypedHandlerFunction.
+func (h *Hub) EventForwarder(channel string) events.TypedEventHandlerType {
+	return func(eventType string, body []byte) error {
+		return h.Broadcast(channel, eventType, json.RawMessage(body))
+	}
+}
+
+// serve runs a connection until the client disconnects.
+func (h *Hub) serve(conn *websocket.Conn) {
+	s := &socket{
+		id:       uuid.New().String(),
+		conn:     conn,
+		outgoing: make(chan []byte, sendBufferSize),
+		closed:   make(chan struct{}),
+		calls:    make(chan struct{}, h.maxCalls),
+		channels: make(map[string]bool),
+	}
+
+	h.mu.Lock()
+	h.sockets[s.id] = s
+	h.mu.Unlock()
+
+	defer h.disconnect(s)
+
+	go s.writeLoop()
+
+	if payload, err := json.Marshal(eventMessage(EventConnected, map[string]string{"socket_id": s.id})); err == nil {
+		s.send(payload)
+	}
+
+	conn.SetReadLimit(1 << 20)
+	_ = conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("websocket read error: %v", err)
+			}
+			return
+		}
+
+		var req request
+		if err := json.Unmarshal(data, &req); err != nil {
+			s.reply(errorMessage("", "BadRequest", err.Error()))
+			continue
+		}
+
+		select {
+		case s.calls <- struct{}{}:
+			go func() {
+				defer func() { <-s.calls }()
+				h.call(s, req)
+			}()
+		default:
+			s.reply(errorMessage(req.CorrelationID, "TooManyCalls", ErrTooManyCalls.Error()))
+		}
+	}
+}
+
+// call runs a client method and replies with its result.
+func (h *Hub) call(s *socket, req request) {
+	h.mu.RLock()
+	method, ok := h.methods[req.Method]
+	h.mu.RUnlock()
+
+	if !ok {
+		s.reply(errorMessage(req.CorrelationID, "MethodNotFound", fmt.Sprintf("%v: %s", ErrMethodNotFound, req.Method)))
+		return
+	}
+
+	result, err := method(s.id, req.Data)
+	if err != nil {
+		log.Printf("websocket method %s failed: %v", req.Method, err)
+		errType, message := clientError(err)
+		s.reply(errorMessage(req.CorrelationID, errType, message))
+		return
+	}
+
+	s.reply(resultMessage(req.CorrelationID, result))
+}
+
+// disconnect removes the socket from the hub and its channels.
+func (h *Hub) disconnect(s *socket) {
+	h.mu.Lock()
+	for channel := range s.channels {
+		h.unsubscribe(s.id, channel)
+	}
+	delete(h.sockets, s.id)
+	h.mu.Unlock()
+
+	s.close()
+}
+
+// clientError returns the type and message of a method error sent to clients.
+// Remote Nameko exceptions keep their type and value, without the exception
+// path and arguments; other errors get a generic message so internals are not
+// leaked.
+func clientError(err error) (string, string) {
+	var remoteErr *rpc.RemoteError
+	if errors.As(err, &remoteErr) {
+		errType := remoteErr.ExcType
+		if errType == "" {
+			errType = "Error"
+		}
+		return errType, remoteErr.Value
+	}
+	return "Error", "internal error"
+}
+
+func decodeChannel(data json.RawMessage) (string, error) {
+	var req channelRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return "", err
+	}
+	if req.Channel == "" {
+		return "", errors.New("channel is required")
+	}
+	return req.Channel, nil
+}
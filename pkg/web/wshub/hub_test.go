@@ -0,0 +1,132 @@
+package wshub
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/fasthttp/websocket"
+	"github.com/joejoe-am/namego/pkg/rpc"
+	"github.com/joejoe-am/namego/pkg/web"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+	"net"
+	"testing"
+	"time"
+)
+
+func dial(t *testing.T, hub *Hub) *websocket.Conn {
+	server := web.New()
+	server.Get("/ws", hub.Handler())
+
+	ln := fasthttputil.NewInmemoryListener()
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() { _ = fasthttp.Serve(ln, server.Handler()) }()
+
+	dialer := websocket.Dialer{NetDial: func(network, addr string) (net.Conn, error) { return ln.Dial() }}
+	conn, _, err := dialer.Dial("ws://hub/ws", nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func read(t *testing.T, conn *websocket.Conn) message {
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	var msg message
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	return msg
+}
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+	hub.RegisterMethod("echo", func(socketID string, data json.RawMessage) (interface{}, error) {
+		return data, nil
+	})
+
+	conn := dial(t, hub)
+
+	if msg := read(t, conn); msg.Type != typeEvent || msg.Event != EventConnected {
+		t.Fatalf("expected connected event, got %+v", msg)
+	}
+
+	_ = conn.WriteJSON(map[string]interface{}{"method": "echo", "data": "hi", "correlation_id": "1"})
+	if msg := read(t, conn); msg.CorrelationID != "1" || !*msg.Success || msg.Data != "hi" {
+		t.Errorf("expected echo result, got %+v", msg)
+	}
+
+	_ = conn.WriteJSON(map[string]interface{}{"method": "missing", "correlation_id": "2"})
+	if msg := read(t, conn); *msg.Success || msg.Error == nil || msg.Error.Type != "MethodNotFound" {
+		t.Errorf("expected MethodNotFound error, got %+v", msg)
+	}
+
+	_ = conn.WriteJSON(map[string]interface{}{"method": MethodSubscribe, "data": map[string]string{"channel": "orders"}, "correlation_id": "3"})
+	if msg := read(t, conn); !*msg.Success {
+		t.Fatalf("expected subscription to succeed, got %+v", msg)
+	}
+
+	if err := hub.EventForwarder("orders")("ORDER_CREATED", []byte(`{"id": 7}`)); err != nil {
+		t.Fatalf("failed to broadcast: %v", err)
+	}
+	msg := read(t, conn)
+	if msg.Event != "ORDER_CREATED" || msg.Data.(map[string]interface{})["id"] != float64(7) {
+		t.Errorf("expected ORDER_CREATED event, got %+v", msg)
+	}
+}
+
+func TestHubTooManyCalls(t *testing.T) {
+	release := make(chan struct{})
+	hub := NewHub()
+	hub.maxCalls = 1
+	hub.RegisterMethod("block", func(socketID string, data json.RawMessage) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+
+	conn := dial(t, hub)
+	read(t, conn)
+
+	_ = conn.WriteJSON(map[string]interface{}{"method": "block", "correlation_id": "1"})
+	_ = conn.WriteJSON(map[string]interface{}{"method": "block", "correlation_id": "2"})
+	if msg := read(t, conn); msg.CorrelationID != "2" || *msg.Success || msg.Error.Type != "TooManyCalls" {
+		t.Errorf("expected TooManyCalls error for the second call, got %+v", msg)
+	}
+
+	close(release)
+	if msg := read(t, conn); msg.CorrelationID != "1" || !*msg.Success {
+		t.Errorf("expected the first call to succeed, got %+v", msg)
+	}
+}
+
+func TestHubMethodErrors(t *testing.T) {
+	hub := NewHub()
+	hub.RegisterMethod("remote", func(socketID string, data json.RawMessage) (interface{}, error) {
+		return nil, &rpc.RemoteError{ExcType: "NotFound", ExcPath: "orders.exceptions.NotFound", ExcArgs: []interface{}{"secret"}, Value: "no order 7"}
+	})
+	hub.RegisterMethod("local", func(socketID string, data json.RawMessage) (interface{}, error) {
+		return nil, errors.New("database password is hunter2")
+	})
+
+	conn := dial(t, hub)
+	read(t, conn)
+
+	tests := []struct {
+		method          string
+		expectedType    string
+		expectedMessage string
+	}{
+		{"remote", "NotFound", "no order 7"},
+		{"local", "Error", "internal error"},
+	}
+
+	for _, test := range tests {
+		_ = conn.WriteJSON(map[string]interface{}{"method": test.method, "correlation_id": test.method})
+		msg := read(t, conn)
+		if msg.Error == nil || msg.Error.Type != test.expectedType || msg.Error.Message != test.expectedMessage {
+			t.Errorf("%s: expected %s error %q, got %+v", test.method, test.expectedType, test.expectedMessage, msg.Error)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package wshub
+
+import "encoding/json"
+
+// request is a method call sent by a client.
+type request struct {
+	Method        string          `json:"method"`
+	Data          json.RawMessage `json:"data"`
+	CorrelationID string          `json:"correlation_id"`
+}
+
+// message is sent to clients, either as the result of a method call
+// (Type "result") or as a pushed event (Type "event").
+type message struct {
+	Type          string        `json:"type"`
+	CorrelationID string        `json:"correlation_id,omitempty"`
+	Success       *bool         `json:"success,omitempty"`
+	Data          interface{}   `json:"data,omitempty"`
+	Error         *errorPayload `json:"error,omitempty"`
+	Event         string        `json:"event,omitempty"`
+}
+
+type errorPayload struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// channelRequest is the data of the built-in subscribe and unsubscribe methods.
+type channelRequest struct {
+	Channel string `json:"channel"`
+}
+
+const (
+	typeResult = "result"
+	typeEvent  = "event"
+
+	// EventConnected is pushed to each client after it connects, with the
+	// socket ID as data.
+	EventConnected = "connected"
+
+	MethodSubscribe   = "subscribe"
+	MethodUnsubscribe = "unsubscribe"
+)
+
+func resultMessage(correlationID string, data interface{}) message {
+	success := true
+	return message{Type: typeResult, CorrelationID: correlationID, Success: &success, Data: data}
+}
+
+func errorMessage(correlationID, errType, errMessage string) message {
+	success := false
+	return message{
+		Type:          typeResult,
+		CorrelationID: correlationID,
+		Success:       &success,
+		Error:         &errorPayload{Type: errType, Message: errMessage},
+	}
+}
+
+func eventMessage(event string, data interface{}) message {
+	return message{Type: typeEvent, Event: event, Data: data}
+}
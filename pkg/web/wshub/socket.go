@@ -0,0 +1,72 @@
+package wshub
+
+import (
+	"encoding/json"
+	"github.com/fasthttp/websocket"
+	"log"
+	"sync"
+	"time"
+)
+
+// socket is one connected client. Writes go through outgoing so broadcasts
+// never block on a slow client, which is disconnected once its buffer is full.
+type socket struct {
+	id       string
+	conn     *websocket.Conn
+	outgoing chan []byte
+	closed   chan struct{}
+	calls    chan struct{} // Semaphore bounding in-flight calls
+	once     sync.Once
+	channels map[string]bool // Guarded by Hub.mu
+}
+
+func (s *socket) send(payload []byte) {
+	select {
+	case <-s.closed:
+	case s.outgoing <- payload:
+	default:
+		log.Printf("websocket %s is too slow, disconnecting", s.id)
+		s.close()
+	}
+}
+
+func (s *socket) reply(msg message) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("failed to serialize websocket message: %v", err)
+		payload, _ = json.Marshal(errorMessage(msg.CorrelationID, "Error", "internal error"))
+	}
+	s.send(payload)
+}
+
+func (s *socket) close() {
+	s.once.Do(func() {
+		close(s.closed)
+		// Unblocks the read loop in Hub.serve.
+		_ = s.conn.Close()
+	})
+}
+
+func (s *socket) writeLoop() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.closed:
+			return
+		case payload := <-s.outgoing:
+			_ = s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := s.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				s.close()
+				return
+			}
+		case <-ticker.C:
+			_ = s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := s.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				s.close()
+				return
+			}
+		}
+	}
+}